@@ -0,0 +1,150 @@
+// Package cache remembers which files are already on an Immich server, keyed by
+// the same content checksum Immich deduplicates on, and the IDs of the albums
+// they were added to.
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// cacheVersion is the current on-disk cache format. Version 1 (unversioned) was a
+// flat map keyed by a file's name and size, which collides across cameras, so its
+// entries are dropped on load.
+const cacheVersion = 2
+
+// Cache maps file checksums to Immich asset IDs and album names to album IDs. It
+// is safe for concurrent use by upload workers.
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]string // SHA-1 hex of file content → immich asset ID
+	albums  map[string]string // album name → immich album ID
+	path    string
+	dirty   bool // entries changed since the last flush
+}
+
+// cacheFile is the on-disk layout of a Cache.
+type cacheFile struct {
+	Version int               `json:"version"`
+	Assets  map[string]string `json:"assets"`
+	Legacy  map[string]string `json:"legacy,omitempty"` // version 1 entries, only read to drop them
+	Albums  map[string]string `json:"albums,omitempty"`
+}
+
+// DefaultPath is the cache file under the user's cache directory. Run journals and
+// the run history are kept beside it.
+func DefaultPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "photo-organiser", "uploaded.json")
+}
+
+// Load reads the cache at path. A missing or unreadable file yields an empty
+// cache, since the cache only saves work and the server deduplicates anyway.
+func Load(path string) *Cache {
+	c := &Cache{
+		entries: make(map[string]string),
+		albums:  make(map[string]string),
+		path:    path,
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn().Err(err).Str("path", path).Msg("could not read upload cache")
+		}
+		return c
+	}
+	var file cacheFile
+	var legacy map[string]string
+	if err := json.Unmarshal(data, &file); err == nil && file.Version == cacheVersion {
+		if file.Assets != nil {
+			c.entries = file.Assets
+		}
+		if file.Albums != nil {
+			c.albums = file.Albums
+		}
+		legacy = file.Legacy
+	} else if err := json.Unmarshal(data, &legacy); err != nil {
+		log.Warn().Err(err).Msg("could not parse upload cache, starting fresh")
+		return c
+	}
+	if len(legacy) > 0 || file.Version != cacheVersion {
+		// A name:size entry may be another camera's file, so none is trusted; the
+		// files are hashed and checked with the server like any other.
+		log.Info().Int("entries", len(legacy)).Msg("dropping upload cache entries without a content hash")
+		c.dirty = true
+	}
+	log.Debug().Int("entries", len(c.entries)).Str("path", path).Msg("loaded upload cache")
+	return c
+}
+
+// Has returns the asset ID recorded for the file with checksum key.
+func (c *Cache) Has(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id, ok := c.entries[key]
+	return id, ok
+}
+
+// Album returns the cached ID of the album called name.
+func (c *Cache) Album(name string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id, ok := c.albums[name]
+	return id, ok
+}
+
+// MarkAlbum records name→albumID; an empty albumID forgets the album.
+func (c *Cache) MarkAlbum(name, albumID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if albumID == "" {
+		delete(c.albums, name)
+	} else {
+		c.albums[name] = albumID
+	}
+	c.dirty = true
+}
+
+// Mark records key→assetID in memory. Call Flush to persist; batching the writes
+// avoids rewriting the whole cache file once per uploaded file.
+func (c *Cache) Mark(key, assetID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = assetID
+	c.dirty = true
+}
+
+// Flush persists the cache to disk if it has unsaved changes. It is called after
+// each date group so an interrupted run keeps the progress of completed groups.
+func (c *Cache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return
+	}
+	data, err := json.MarshalIndent(cacheFile{
+		Version: cacheVersion,
+		Assets:  c.entries,
+		Albums:  c.albums,
+	}, "", "  ")
+	if err != nil {
+		log.Warn().Err(err).Msg("could not marshal upload cache")
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		log.Warn().Err(err).Msg("could not create cache directory")
+		return
+	}
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		log.Warn().Err(err).Msg("could not save upload cache")
+		return
+	}
+	c.dirty = false
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUploadCacheRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "uploaded.json")
+
+	cache := Load(path)
+	if _, ok := cache.Has("a:1"); ok {
+		t.Fatal("fresh cache should not contain any key")
+	}
+
+	cache.Mark("a:1", "asset-a")
+	cache.Mark("b:2", "asset-b")
+	cache.Flush()
+
+	reloaded := Load(path)
+	if id, ok := reloaded.Has("a:1"); !ok || id != "asset-a" {
+		t.Errorf("reloaded cache has(a:1) = (%q, %v), want (asset-a, true)", id, ok)
+	}
+	if id, ok := reloaded.Has("b:2"); !ok || id != "asset-b" {
+		t.Errorf("reloaded cache has(b:2) = (%q, %v), want (asset-b, true)", id, ok)
+	}
+}
+
+func TestUploadCacheFlushOnlyWhenDirty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "uploaded.json")
+	cache := Load(path)
+
+	// mark alone must not touch disk; batching is the whole point.
+	cache.Mark("a:1", "asset-a")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("mark should not write the cache file before flush")
+	}
+
+	cache.Flush()
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("flush should have written the cache file: %v", err)
+	}
+}
+
+func TestLoadCacheMissingFile(t *testing.T) {
+	cache := Load(filepath.Join(t.TempDir(), "does-not-exist.json"))
+	if cache == nil || cache.entries == nil {
+		t.Fatal("Load should return an initialised cache for a missing file")
+	}
+	if len(cache.entries) != 0 {
+		t.Errorf("expected empty cache, got %d entries", len(cache.entries))
+	}
+}
+
+func TestLoadCacheCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "uploaded.json")
+	if err := os.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cache := Load(path)
+	if len(cache.entries) != 0 {
+		t.Errorf("corrupt cache should start fresh, got %d entries", len(cache.entries))
+	}
+}
+
+func TestLoadCacheMigratesLegacyFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "uploaded.json")
+	legacy := `{"DSC0001.JPG:1024": "asset-1", "DSC0002.JPG:2048": "asset-2"}`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := Load(path)
+	if len(cache.entries) != 0 {
+		t.Fatalf("got %d entries, want legacy entries dropped", len(cache.entries))
+	}
+	cache.Flush()
+
+	// The rewritten file is in the current format, without the legacy entries.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file cacheFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Version != cacheVersion || len(file.Legacy) != 0 {
+		t.Errorf("rewritten cache = %s (%v), want version %d without legacy entries", data, err, cacheVersion)
+	}
+}
+
+func TestLoadCacheDropsLegacyEntries(t *testing.T) {
+	// A version 2 cache still holding version 1 entries, as written by earlier
+	// releases that only dropped them once the same file was seen again.
+	path := filepath.Join(t.TempDir(), "uploaded.json")
+	data := `{"version": 2, "assets": {"abc": "asset-1"}, "legacy": {"DSC0002.JPG:2048": "asset-2"}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	Load(path).Flush()
+	reloaded := Load(path)
+	if id, ok := reloaded.Has("abc"); !ok || id != "asset-1" {
+		t.Errorf("checksum entry lost: (%q, %v)", id, ok)
+	}
+	if reloaded.dirty {
+		t.Error("legacy entries should have been dropped when the cache was first flushed")
+	}
+}
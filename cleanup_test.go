@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/DistroByte/photo-organiser/pipeline"
+)
+
+func TestDevNullStdinIsUnattended(t *testing.T) {
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = devNull
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		devNull.Close()
+	})
+
+	if stdinIsTerminal() {
+		t.Error("stdin on /dev/null reported as a terminal")
+	}
+	if !newPipeline().Unattended {
+		t.Error("a pipeline with stdin on /dev/null must not prompt")
+	}
+}
+
+func TestCheckCleanupPolicy(t *testing.T) {
+	t.Cleanup(func() { cleanupPolicy, cleanupUnattended = "", "" })
+	for _, tc := range []struct {
+		policy, unattended string
+		ok                 bool
+	}{
+		{pipeline.CleanupAsk, pipeline.CleanupNever, true},
+		{pipeline.CleanupVerified, pipeline.CleanupAlways, true},
+		{"sometimes", pipeline.CleanupNever, false},
+		{pipeline.CleanupAsk, pipeline.CleanupAsk, false},
+	} {
+		cleanupPolicy, cleanupUnattended = tc.policy, tc.unattended
+		if err := checkCleanupPolicy(); (err == nil) != tc.ok {
+			t.Errorf("checkCleanupPolicy(%q, %q) = %v, want ok=%v", tc.policy, tc.unattended, err, tc.ok)
+		}
+	}
+}
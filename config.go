@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/DistroByte/photo-organiser/organise"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// config is the on-disk configuration file. Every setting is keyed by the name of
+// the persistent flag it fills in, so anything that can be passed on the command
+// line can also live in a profile.
+type config struct {
+	Defaults map[string]string              `yaml:"defaults"` // applied to every command
+	Profiles map[string]map[string]string   `yaml:"profiles"` // applied by name via --profile
+	Cameras  map[string]organise.Definition `yaml:"cameras"`  // extra camera subcommands
+	Cards    map[string]string              `yaml:"cards"`    // camera (or "auto") per volume label or UUID, for watch
+}
+
+// defaultConfigPath returns $PHOTO_ORGANISER_CONFIG if set, otherwise
+// photo-organiser/config.yaml under the user's config directory.
+func defaultConfigPath() string {
+	if path := os.Getenv("PHOTO_ORGANISER_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "photo-organiser", "config.yaml")
+}
+
+// loadConfig reads the config file at path. A missing file is not an error and
+// yields an empty config.
+func loadConfig(path string) (*config, error) {
+	cfg := &config{}
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// addConfigCameras registers a subcommand for each camera declared in the config
+// file. It must be called once every built-in command has been added to root.
+func addConfigCameras(root *cobra.Command, cameras map[string]organise.Definition) error {
+	for name, def := range cameras {
+		// cobra adds help and completion itself, only when executing.
+		if cmd, _, err := root.Find([]string{name}); (err == nil && cmd != root) || name == "help" || name == "completion" {
+			return fmt.Errorf("camera %q clashes with an existing command", name)
+		}
+		camera, err := def.Camera(name)
+		if err != nil {
+			return err
+		}
+		short := def.Short
+		if short == "" {
+			short = "Organise " + name + " camera photos"
+		}
+		root.AddCommand(newCameraCmd(name, short, camera))
+	}
+	return nil
+}
+
+// applyConfig fills in every flag that was not given on the command line. Settings
+// are layered: defaults first, then the profile named after the subcommand (so a
+// "sony" profile applies to `photo-organiser sony`), then each --profile in order.
+// Later layers override earlier ones, and explicit flags override them all.
+func applyConfig(cmd *cobra.Command, cfg *config, names []string) error {
+	layers := []map[string]string{cfg.Defaults}
+	if profile, ok := cfg.Profiles[cmd.Name()]; ok {
+		layers = append(layers, profile)
+	}
+	for _, name := range names {
+		profile, ok := cfg.Profiles[name]
+		if !ok {
+			return fmt.Errorf("unknown profile %q", name)
+		}
+		layers = append(layers, profile)
+	}
+
+	// Resolve the final value per flag before setting anything: pflag slice flags
+	// append on repeated Set calls, but a later layer should replace an earlier one.
+	values := make(map[string]string)
+	for _, layer := range layers {
+		for name, value := range layer {
+			values[name] = value
+		}
+	}
+
+	flags := cmd.Flags()
+	explicit := make(map[string]bool)
+	flags.Visit(func(f *pflag.Flag) { explicit[f.Name] = true })
+
+	for name, value := range values {
+		if name == "profile" {
+			return fmt.Errorf("profiles cannot set %q", name)
+		}
+		if flags.Lookup(name) == nil {
+			return fmt.Errorf("unknown setting %q", name)
+		}
+		if explicit[name] {
+			continue
+		}
+		if err := flags.Set(name, value); err != nil {
+			return fmt.Errorf("setting %s: %w", name, err)
+		}
+	}
+	return nil
+}
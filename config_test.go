@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DistroByte/photo-organiser/organise"
+	"github.com/spf13/cobra"
+)
+
+// newConfigTestCmd builds a "sony" subcommand under a root with two string
+// persistent flags, parsed with args, mirroring how main wires the real flags.
+func newConfigTestCmd(t *testing.T, args ...string) (*cobra.Command, *string, *string) {
+	t.Helper()
+	var host, user string
+	root := &cobra.Command{Use: "photo-organiser"}
+	root.PersistentFlags().StringVar(&host, "host", "", "")
+	root.PersistentFlags().StringVar(&user, "user", "nobody", "")
+	sub := &cobra.Command{Use: "sony", Run: func(*cobra.Command, []string) {}}
+	root.AddCommand(sub)
+	root.SetArgs(append([]string{"sony"}, args...))
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	return sub, &host, &user
+}
+
+func TestApplyConfigLayering(t *testing.T) {
+	cfg := &config{
+		Defaults: map[string]string{"host": "default.host", "user": "default-user"},
+		Profiles: map[string]map[string]string{
+			"sony": {"host": "camera.host"},
+			"nas":  {"host": "nas.host"},
+		},
+	}
+
+	cmd, host, user := newConfigTestCmd(t)
+	if err := applyConfig(cmd, cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+	if *host != "camera.host" || *user != "default-user" {
+		t.Errorf("subcommand profile: host=%q user=%q, want camera.host, default-user", *host, *user)
+	}
+
+	cmd, host, _ = newConfigTestCmd(t)
+	if err := applyConfig(cmd, cfg, []string{"nas"}); err != nil {
+		t.Fatal(err)
+	}
+	if *host != "nas.host" {
+		t.Errorf("--profile nas: host=%q, want nas.host", *host)
+	}
+
+	cmd, host, _ = newConfigTestCmd(t, "--host", "flag.host")
+	if err := applyConfig(cmd, cfg, []string{"nas"}); err != nil {
+		t.Fatal(err)
+	}
+	if *host != "flag.host" {
+		t.Errorf("explicit flag: host=%q, want flag.host", *host)
+	}
+}
+
+func TestApplyConfigErrors(t *testing.T) {
+	cmd, _, _ := newConfigTestCmd(t)
+	if err := applyConfig(cmd, &config{}, []string{"missing"}); err == nil {
+		t.Error("expected error for an unknown profile")
+	}
+
+	cmd, _, _ = newConfigTestCmd(t)
+	cfg := &config{Defaults: map[string]string{"no-such-flag": "x"}}
+	if err := applyConfig(cmd, cfg, nil); err == nil {
+		t.Error("expected error for an unknown setting")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	cfg, err := loadConfig(filepath.Join(dir, "missing.yaml"))
+	if err != nil || cfg == nil {
+		t.Fatalf("missing file should yield an empty config, got (%v, %v)", cfg, err)
+	}
+
+	path := filepath.Join(dir, "config.yaml")
+	data := "defaults:\n  dry-run: true\nprofiles:\n  nas:\n    host: nas.local\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Defaults["dry-run"] != "true" || cfg.Profiles["nas"]["host"] != "nas.local" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+
+	if err := os.WriteFile(path, []byte("defaults: [not, a, map]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadConfig(path); err == nil {
+		t.Error("expected error for a malformed config file")
+	}
+}
+
+func TestAddConfigCamerasRejectsClashes(t *testing.T) {
+	root := &cobra.Command{Use: "photo-organiser"}
+	root.AddCommand(&cobra.Command{Use: "sync"})
+	t.Cleanup(func() { delete(camerasByName, "fuji") })
+
+	def := organise.Definition{EXIFFallback: true}
+	for _, name := range []string{"sync", "help", "completion"} {
+		if err := addConfigCameras(root, map[string]organise.Definition{name: def}); err == nil {
+			t.Errorf("camera %q was registered over a built-in command", name)
+		}
+	}
+	if err := addConfigCameras(root, map[string]organise.Definition{"fuji": def}); err != nil {
+		t.Fatal(err)
+	}
+	if cmd, _, err := root.Find([]string{"fuji"}); err != nil || cmd.Name() != "fuji" {
+		t.Errorf("fuji camera not registered: %v", err)
+	}
+	if n := len(root.Commands()); n != 2 {
+		t.Errorf("root has %d commands, want sync and fuji", n)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"cmp"
+	"context"
+
+	"github.com/DistroByte/photo-organiser/organise"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func runAuto(cmd *cobra.Command, args []string) error {
+	return offloadDetected(cmd.Context())
+}
+
+// offloadDetected processes every camera detected on the card. A failing camera
+// does not stop the others, but an interruption does; the first failure is returned.
+func offloadDetected(ctx context.Context) error {
+	var uploaded bool
+	p := newPipeline()
+	err := p.WithCard(false, func() error {
+		names := organise.Detect(directory)
+		if len(names) == 0 {
+			log.Warn().Str("mount_point", directory).Msg("no known camera layout found on card")
+		}
+
+		var firstErr error
+		for _, name := range names {
+			camera, ok := builtinCamera(name)
+			if !ok {
+				continue
+			}
+			transferers, err := selectBackends(camera, backends)
+			if err != nil {
+				log.Warn().Err(err).Str("camera", name).Msg("skipping detected camera")
+				continue
+			}
+			source := camera.SourceDir(directory)
+			log.Info().Str("camera", name).Str("source", source).Msg("detected camera")
+			if err := p.Process(ctx, camera, source, transferers); err != nil {
+				log.Error().Err(err).Str("camera", name).Msg("camera failed")
+				if ctx.Err() != nil {
+					return err
+				}
+				firstErr = cmp.Or(firstErr, err)
+			}
+			if usesBackend(transferers, backendImmich) {
+				uploaded = true
+			}
+		}
+		return firstErr
+	})
+	if err != nil {
+		return err
+	}
+
+	if uploaded && immichLibrary != "" {
+		return triggerSync(ctx)
+	}
+	return nil
+}
+
+func builtinCamera(name string) (organise.Camera, bool) {
+	for _, cc := range cameraCmds {
+		if cc.camera.Name == name {
+			return cc.camera, true
+		}
+	}
+	return organise.Camera{}, false
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/DistroByte/photo-organiser/pipeline"
+)
+
+// Process exit codes, so wrappers such as udev rules or systemd units can tell a
+// card that would not mount from a transfer that needs resuming.
+const (
+	exitFailure  = 1 // anything else, including invalid flags or config
+	exitMount    = 3
+	exitGroup    = 4
+	exitTransfer = 5
+	exitVerify   = 6
+	exitCleanup  = 7
+
+	exitInterrupted = 130 // stopped by SIGINT or SIGTERM, as a shell reports Ctrl-C
+)
+
+// stageExitCodes maps each pipeline stage to the process exit code for it.
+var stageExitCodes = map[pipeline.Stage]int{
+	pipeline.StageMount:    exitMount,
+	pipeline.StageGroup:    exitGroup,
+	pipeline.StageTransfer: exitTransfer,
+	pipeline.StageVerify:   exitVerify,
+	pipeline.StageCleanup:  exitCleanup,
+}
+
+// exitCode returns the process exit code for err, which is 0 only for nil. An
+// interrupted run exits with exitInterrupted whichever stage it was in.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if errors.Is(err, context.Canceled) {
+		return exitInterrupted
+	}
+	var pe *pipeline.Error
+	if errors.As(err, &pe) {
+		if code, ok := stageExitCodes[pe.Stage]; ok {
+			return code
+		}
+	}
+	return exitFailure
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/DistroByte/photo-organiser/pipeline"
+)
+
+func TestExitCode(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want int
+	}{
+		{nil, 0},
+		{errors.New("bad flag"), exitFailure},
+		{pipeline.StageError(pipeline.StageMount, errors.New("no such device")), exitMount},
+		{pipeline.StageError(pipeline.StageGroup, errors.New("unreadable")), exitGroup},
+		{fmt.Errorf("camera sony: %w", pipeline.StageError(pipeline.StageTransfer, errors.New("boom"))), exitTransfer},
+		{pipeline.StageError(pipeline.StageVerify, errors.New("missing")), exitVerify},
+		{pipeline.StageError(pipeline.StageCleanup, errors.New("read-only")), exitCleanup},
+		{pipeline.StageError(pipeline.StageTransfer, fmt.Errorf("transfer interrupted: %w", context.Canceled)), exitInterrupted},
+	} {
+		if got := exitCode(tc.err); got != tc.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tc.err, got, tc.want)
+		}
+	}
+}
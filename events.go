@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/DistroByte/photo-organiser/pipeline"
+	"github.com/DistroByte/photo-organiser/progress"
+	"github.com/DistroByte/photo-organiser/transfer"
+)
+
+// Output formats for --output.
+const (
+	outputText = "text" // human-readable logs only
+	outputJSON = "json" // one JSON event per line on stdout, logs on stderr
+)
+
+func checkOutputFormat() error {
+	if outputFormat != outputText && outputFormat != outputJSON {
+		return fmt.Errorf("unknown --output format %q (want %s or %s)", outputFormat, outputText, outputJSON)
+	}
+	return nil
+}
+
+// Events emitted by the CLI itself rather than a transfer backend.
+const (
+	eventSummary   = "summary"    // a camera's run as recorded in the history, after the transfer events
+	eventRun       = "run"        // a past run listed by the history command
+	eventPlanGroup = "plan_group" // a date group the plan command would transfer
+)
+
+// event is one line of --output=json: a transfer event, a run summary or past
+// run, or a planned group.
+type event struct {
+	Time time.Time `json:"time"`
+	transfer.Event
+	Run    *pipeline.Record `json:"run,omitempty"`
+	Bytes  int64            `json:"bytes,omitempty"`  // plan_group: total size
+	Types  map[string]int   `json:"types,omitempty"`  // plan_group: file count per extension
+	Cached int              `json:"cached,omitempty"` // plan_group: files the upload cache knows
+}
+
+var (
+	eventMu  sync.Mutex
+	eventOut io.Writer = os.Stdout
+)
+
+// emit writes ev as a line of JSON on stdout when --output=json is set. It is
+// safe for concurrent use by upload workers.
+func emit(ev event) {
+	if outputFormat != outputJSON {
+		return
+	}
+	ev.Time = time.Now()
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	eventMu.Lock()
+	defer eventMu.Unlock()
+	_, _ = eventOut.Write(append(line, '\n'))
+}
+
+// emitTransferEvent emits an event reported by a transfer backend.
+func emitTransferEvent(ev transfer.Event) {
+	emit(event{Event: ev})
+}
+
+// textOut is where output meant for people goes, such as rsync's progress and
+// the cleanup prompt: stdout normally, but stderr when stdout carries events.
+func textOut() io.Writer {
+	if outputFormat == outputJSON {
+		return progress.Stderr
+	}
+	return os.Stdout
+}
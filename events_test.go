@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/DistroByte/photo-organiser/transfer"
+)
+
+// captureEvents switches to --output=json and returns a func that decodes every
+// event emitted so far.
+func captureEvents(t *testing.T) func() []event {
+	t.Helper()
+	var buf bytes.Buffer
+	orig := eventOut
+	outputFormat, eventOut = outputJSON, &buf
+	t.Cleanup(func() { outputFormat, eventOut = "", orig })
+	return func() []event {
+		var events []event
+		scanner := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+		for scanner.Scan() {
+			var ev event
+			if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+				t.Fatalf("invalid event line %q: %v", scanner.Text(), err)
+			}
+			events = append(events, ev)
+		}
+		return events
+	}
+}
+
+func TestEmitTransferEvent(t *testing.T) {
+	events := captureEvents(t)
+
+	emitTransferEvent(transfer.Event{Name: transfer.EventFileUploaded, Backend: "immich", File: "/card/a.jpg", AssetID: "asset-1"})
+
+	got := events()
+	if len(got) != 1 {
+		t.Fatalf("got %d events, want 1", len(got))
+	}
+	if ev := got[0]; ev.Name != transfer.EventFileUploaded || ev.AssetID != "asset-1" || ev.Time.IsZero() {
+		t.Errorf("event = %+v", ev)
+	}
+}
+
+func TestEmitIsSilentForTextOutput(t *testing.T) {
+	var buf bytes.Buffer
+	orig := eventOut
+	outputFormat, eventOut = outputText, &buf
+	t.Cleanup(func() { outputFormat, eventOut = "", orig })
+
+	emit(event{Event: transfer.Event{Name: eventSummary}})
+	if buf.Len() != 0 {
+		t.Errorf("text output emitted %q", buf.String())
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/DistroByte/photo-organiser/pipeline"
+	"github.com/DistroByte/photo-organiser/progress"
+	"github.com/DistroByte/photo-organiser/transfer"
+	"github.com/spf13/cobra"
+)
+
+// runHistory lists past runs. With a run ID it prints that run in full; with a
+// card UUID or volume label it lists only that card's runs.
+func runHistory(cmd *cobra.Command, args []string) error {
+	records, err := newPipeline().History()
+	if err != nil {
+		return fmt.Errorf("reading run history: %w", err)
+	}
+
+	if len(args) == 1 {
+		for _, rec := range records {
+			if rec.ID == args[0] {
+				if outputFormat == outputJSON {
+					emit(event{Event: transfer.Event{Name: eventRun, Camera: rec.Camera}, Run: &rec})
+					return nil
+				}
+				out, _ := json.MarshalIndent(rec, "", "  ")
+				fmt.Println(string(out))
+				return nil
+			}
+		}
+		var matched []pipeline.Record
+		for _, rec := range records {
+			if rec.Card == args[0] || strings.EqualFold(rec.Label, args[0]) {
+				matched = append(matched, rec)
+			}
+		}
+		records = matched
+		if len(records) == 0 {
+			_, _ = fmt.Fprintf(textOut(), "No runs recorded for %q.\n", args[0])
+			return nil
+		}
+	}
+	if len(records) == 0 {
+		_, _ = fmt.Fprintln(textOut(), "No runs recorded yet.")
+		return nil
+	}
+	if outputFormat == outputJSON {
+		for _, rec := range records {
+			emit(event{Event: transfer.Event{Name: eventRun, Camera: rec.Camera}, Run: &rec})
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tSTARTED\tCAMERA\tCARD\tFILES\tSENT\tSIZE\tCLEANED\tBACKENDS\tDURATION\tSTATUS")
+	for _, rec := range records {
+		card := rec.Card
+		if rec.Label != "" {
+			card = rec.Label + " (" + rec.Card + ")"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%s\t%d\t%s\t%s\t%s\n",
+			rec.ID,
+			rec.Started.Local().Format("2006-01-02 15:04"),
+			rec.Camera,
+			card,
+			rec.Files,
+			rec.Transferred,
+			progress.HumanBytes(rec.Bytes),
+			rec.Cleaned,
+			strings.Join(rec.Backends, ","),
+			time.Duration(rec.DurationSeconds*float64(time.Second)).Round(time.Second).String(),
+			rec.Status(),
+		)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DistroByte/photo-organiser/cache"
+	"github.com/DistroByte/photo-organiser/pipeline"
+)
+
+func TestRunHistoryEvents(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	path := filepath.Join(filepath.Dir(cache.DefaultPath()), "history.jsonl")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var lines []byte
+	for _, rec := range []pipeline.Record{
+		{ID: "20240601-120000-sony", Started: time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC), Camera: "sony", Files: 3, Transferred: 3},
+		{ID: "20240603-090000-dji", Camera: "dji", Failed: 1},
+	} {
+		line, _ := json.Marshal(rec)
+		lines = append(append(lines, line...), '\n')
+	}
+	if err := os.WriteFile(path, lines, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// With --output=json, stdout carries one run event per line, not the table.
+	events := captureEvents(t)
+	if err := runHistory(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	got := events()
+	if len(got) != 2 || got[0].Name != eventRun || got[0].Run == nil || got[1].Run.ID != "20240603-090000-dji" {
+		t.Errorf("history events = %+v", got)
+	}
+}
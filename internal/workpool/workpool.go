@@ -0,0 +1,24 @@
+// Package workpool runs a function over a slice with a bounded number of
+// goroutines, for hashing and uploading files in parallel.
+package workpool
+
+import "sync"
+
+// Each calls fn for every item using workers goroutines, at least one, and
+// returns once every call has.
+func Each[T any](workers int, items []T, fn func(T)) {
+	var wg sync.WaitGroup
+	queue := make(chan T)
+	for range max(workers, 1) {
+		wg.Go(func() {
+			for item := range queue {
+				fn(item)
+			}
+		})
+	}
+	for _, item := range items {
+		queue <- item
+	}
+	close(queue)
+	wg.Wait()
+}
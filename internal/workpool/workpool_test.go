@@ -0,0 +1,36 @@
+package workpool
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEach(t *testing.T) {
+	var sum, inFlight, peak atomic.Int64
+	items := []int64{1, 2, 3, 4, 5, 6, 7, 8}
+	Each(4, items, func(n int64) {
+		cur := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if cur <= p || peak.CompareAndSwap(p, cur) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		sum.Add(n)
+	})
+	if sum.Load() != 36 {
+		t.Errorf("sum = %d, want every item once (36)", sum.Load())
+	}
+	if p := peak.Load(); p < 2 || p > 4 {
+		t.Errorf("peak workers = %d, want between 2 and 4", p)
+	}
+
+	var calls atomic.Int64
+	Each(0, items[:2], func(int64) { calls.Add(1) })
+	if calls.Load() != 2 {
+		t.Errorf("zero workers made %d calls, want 2", calls.Load())
+	}
+}
@@ -3,29 +3,52 @@ photo-organiser is a CLI tool that organises camera photos into a directory stru
 
 Available Commands:
 
+	auto        Detect the camera(s) from the card layout and organise their files
 	canon       Organise Canon camera photos
 	completion  Generate the autocompletion script for the specified shell
 	dji         Organise DJI camera (action/drone) photos
 	help        Help about any command
+	history     List past runs, or show one run or one card's runs
+	plan        Show what a run would transfer, mounting the card read-only
+	purge       Delete trash entries older than --trash-retention days
+	resume      Continue the last unfinished run for the card
 	sony        Organise Sony camera photos (default)
 	sync        Trigger an immich sync
 	update      Update photo-organiser to the latest release
 	version     Print version information
+	watch       Import configured cards as they are attached, without prompting
 
 Flags:
 
-	    --device string        device to mount (default "/dev/sdd1")
+	    --album string         immich album name template per date group, e.g. "{{.Date}} {{.Camera}}"
+	    --backend strings      transfer backend(s): rsync, immich, local (default: first configured)
+	    --cleanup string       delete transferred files from the source: ask, always, never, verified (default "ask")
+	    --cleanup-unattended string
+	                           cleanup policy used instead of ask when stdin is not a terminal (default "never")
+	    --concurrency int      number of parallel immich uploads (default 4)
+	    --dest-template string destination directory template for rsync and local copies (default "{{.Date}}")
+	    --device string        device to mount, or LABEL=<label> or UUID=<uuid> (default "/dev/sdd1")
 	    --directory string     mount point (default "/dev/camera")
 	-n, --dry-run              will not move files, copy them to the remote, or cleanup source directories
 	-h, --help                 help for photo-organiser
 	    --host string          remote host for rsync
 	    --key string           immich api key (use instead of --host/--remote-path for direct upload)
+	    --local-path string    local or mounted destination directory (copy without a network)
 	    --mount-type string    filesystem type for mounting (default "exfat")
+	    --output string        output format: text, or json for one event per line on stdout (logs stay on stderr) (default "text")
+	    --poll-interval duration
+	                           how often watch looks for newly attached cards (default 2s)
+	    --profile strings      config profile(s) to apply, in order
 	    --remote-path string   remote destination path for rsync
+	    --retries int          retries for failed immich requests (5xx, 429, network errors) (default 3)
+	    --retry-delay duration initial delay between immich retries, doubled per attempt (default 1s)
 	    --server string        immich api base url (e.g. https://immich.local/api)
 	-s, --source string        source directory containing the photos. (default /mount/point/DCIM)
+	    --trash string         move cleaned files into this directory instead of deleting them ("card" for a trash folder on the card)
+	    --trash-retention int  days to keep trashed files before they are purged (0 keeps them forever) (default 30)
 	    --user string          remote user for rsync (default "james")
 	-v, --verbose              enable debug logging
+	    --verify               check every file arrived intact before offering cleanup
 
 Example usage:
 
@@ -34,20 +57,94 @@ Example usage:
 
 	# Upload via rsync over SSH
 	photo-organiser sony --host remote.host --user username --remote-path /path/on/remote
+
+	# Copy to a local or mounted directory, e.g. an external SSD
+	photo-organiser sony --local-path /media/ssd/photos
+
+	# Upload to Immich and add each day's photos to an album such as "2024-06-01 sony"
+	photo-organiser sony --server https://immich.local/api --key <api-key> --album '{{.Date}} {{.Camera}}'
+
+	# Lay out the destination as <year>/<month>/<date> <camera>
+	photo-organiser sony --local-path /media/ssd/photos --dest-template '{{.Year}}/{{.Month}}/{{.Date}} {{.Camera}}'
+
+	# Sync to the NAS and upload to Immich in one run
+	photo-organiser sony --backend rsync,immich --host nas.local --remote-path /photos --server https://immich.local/api --key <api-key>
+
+	# Find the card by its volume label rather than its device node
+	photo-organiser sony --device 'LABEL=SONY A7' --local-path /media/ssd/photos
+
+	# Use settings from the "nas" profile in the config file
+	photo-organiser sony --profile nas
+
+	# Run unattended (e.g. from a udev rule), deleting files only once verified
+	photo-organiser auto --cleanup verified
+
+	# Keep cleaned files in a trash folder on the card for a week
+	photo-organiser sony --trash card --trash-retention 7
+
+	# Import the cards listed under cards in the config file whenever one is attached
+	photo-organiser watch --cleanup verified
+
+Configuration:
+
+Settings are read from $PHOTO_ORGANISER_CONFIG, or config.yaml in the
+photo-organiser directory under the user config directory. Keys are flag names:
+
+	defaults:
+	  user: james
+	profiles:
+	  sony:          # applied automatically to the sony subcommand
+	    device: /dev/sdd1
+	  nas:
+	    host: nas.local
+	    remote-path: /volume1/photos
+
+Flags given on the command line always override the config file. The config
+file can also declare extra cameras, each registered as its own subcommand:
+
+	cameras:
+	  fuji:
+	    source: DCIM/100_FUJI
+	    filename-regex: '^DSCF_(?P<year>\d{4})(?P<month>\d{2})(?P<day>\d{2})_\d+\..+$'
+	    exif-fallback: true
+	    flat-cleanup: true
+	    extensions: [.jpg, .raf]
+
+The watch command imports the cards listed under cards, keyed by volume label
+or filesystem UUID, with the named camera or auto:
+
+	cards:
+	  SONY A7: sony
+	  3A21-0F7C: auto
+
+Exit codes:
+
+	0  success
+	1  any other failure, including invalid flags or config
+	3  the card could not be mounted or unmounted
+	4  files could not be grouped by date
+	5  a transfer failed (the journal allows a resume)
+	6  verification failed, so nothing was cleaned up
+	7  some transferred files could not be cleaned up
+	130  interrupted by Ctrl-C or SIGTERM (the journal allows a resume)
 */
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"net"
 	"net/http"
 	"os"
-	"path/filepath"
+	"os/signal"
 	"runtime/debug"
+	"syscall"
 	"time"
 
+	"github.com/DistroByte/photo-organiser/organise"
+	"github.com/DistroByte/photo-organiser/pipeline"
+	"github.com/DistroByte/photo-organiser/progress"
+	"github.com/DistroByte/photo-organiser/transfer"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -65,129 +162,163 @@ var httpClient = &http.Client{
 }
 
 var (
-	sourceDir     string
-	dryRun        bool
-	verbose       bool
-	remoteUser    string
-	remoteHost    string
-	remotePath    string
-	device        string
-	directory     string
-	mountType     string
-	immichLibrary string
-	immichKey     string
-	immichServer  string
+	sourceDir         string
+	dryRun            bool
+	verbose           bool
+	verify            bool
+	cleanupPolicy     string
+	cleanupUnattended string
+	outputFormat      string
+	trashPath         string
+	trashRetention    int
+	remoteUser        string
+	remoteHost        string
+	remotePath        string
+	localPath         string
+	backends          []string
+	destTemplate      string
+	albumTemplate     string
+	concurrency       int
+	retries           int
+	retryDelay        time.Duration
+	device            string
+	directory         string
+	mountType         string
+	pollInterval      time.Duration
+	immichLibrary     string
+	immichKey         string
+	immichServer      string
+	profileNames      []string
 )
 
-type ImmichError struct {
-	Message    string `json:"message"`
-	ErrType    string `json:"error"`
-	StatusCode int    `json:"statusCode"`
+// cameraCmd is a built-in camera subcommand.
+type cameraCmd struct {
+	use    string
+	short  string
+	camera organise.Camera
+}
+
+var cameraCmds = []cameraCmd{
+	{use: "sony", short: "Organise Sony camera photos (default)", camera: organise.Sony},
+	{use: "sony-video", short: "Transfer Sony camera videos via rsync or local copy", camera: organise.SonyVideo},
+	{use: "dji", short: "Organise DJI camera (action/drone) photos", camera: organise.DJI},
+	{use: "canon", short: "Organise Canon camera photos", camera: organise.Canon},
+	{use: "charmera", short: "Organise Kodak Charmera keychain camera photos", camera: organise.Charmera},
 }
 
 func main() {
-	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: progress.Stderr})
+	transfer.Notify = emitTransferEvent
+
+	cfg, err := loadConfig(defaultConfigPath())
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load config file")
+	}
 
 	rootCmd := &cobra.Command{
 		Use:   "photo-organiser",
 		Short: "Organise camera photos into a directory structure based on the date they were taken.",
 		Long:  `photo-organiser is a CLI tool that organises camera photos into a directory structure based on the date they were taken.`,
 
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Errors are logged once below, with the stage's exit code.
+		SilenceErrors: true,
+		SilenceUsage:  true,
+
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := applyConfig(cmd, cfg, profileNames); err != nil {
+				return fmt.Errorf("applying config file: %w", err)
+			}
+			if err := checkCleanupPolicy(); err != nil {
+				return err
+			}
+			if err := checkOutputFormat(); err != nil {
+				return err
+			}
 			if verbose {
 				zerolog.SetGlobalLevel(zerolog.DebugLevel)
 			} else {
 				zerolog.SetGlobalLevel(zerolog.InfoLevel)
 			}
+			return nil
 		},
 	}
 
-	rootCmd.PersistentFlags().StringVar(&device, "device", "/dev/sdd1", "device to mount")
+	rootCmd.PersistentFlags().StringVar(&device, "device", "/dev/sdd1", "device to mount, or LABEL=<label> or UUID=<uuid>")
 	rootCmd.PersistentFlags().StringVar(&directory, "directory", "/dev/camera", "mount point")
 	rootCmd.PersistentFlags().StringVarP(&sourceDir, "source", "s", "", "source directory containing the photos. (default /mount/point/DCIM)")
 	rootCmd.PersistentFlags().StringVar(&remoteUser, "user", os.Getenv("USER"), "remote user for rsync")
 	rootCmd.PersistentFlags().StringVar(&remoteHost, "host", "", "remote host for rsync")
 	rootCmd.PersistentFlags().StringVar(&remotePath, "remote-path", "", "remote destination path for rsync")
+	rootCmd.PersistentFlags().StringVar(&localPath, "local-path", "", "local or mounted destination directory (copy without a network)")
+	rootCmd.PersistentFlags().StringVar(&destTemplate, "dest-template", organise.DefaultDestTemplate, "destination directory template for rsync and local copies")
+	rootCmd.PersistentFlags().StringVar(&albumTemplate, "album", "", "immich album name template per date group, e.g. \"{{.Date}} {{.Camera}}\"")
+	rootCmd.PersistentFlags().StringSliceVar(&backends, "backend", nil, "transfer backend(s): rsync, immich, local (default: first configured)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "enable debug logging")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output", outputText, "output format: text, or json for one event per line on stdout (logs stay on stderr)")
 	rootCmd.PersistentFlags().BoolVarP(&dryRun, "dry-run", "n", false, "will not move files, copy them to the remote, or cleanup source directories")
+	rootCmd.PersistentFlags().BoolVar(&verify, "verify", false, "check every file arrived intact before offering cleanup")
+	rootCmd.PersistentFlags().StringVar(&cleanupPolicy, "cleanup", pipeline.CleanupAsk, "delete transferred files from the source: ask, always, never, verified")
+	rootCmd.PersistentFlags().StringVar(&cleanupUnattended, "cleanup-unattended", pipeline.CleanupNever, "cleanup policy used instead of ask when stdin is not a terminal")
+	rootCmd.PersistentFlags().StringVar(&trashPath, "trash", "", "move cleaned files into this directory instead of deleting them (\"card\" for a trash folder on the card)")
+	rootCmd.PersistentFlags().IntVar(&trashRetention, "trash-retention", 30, "days to keep trashed files before they are purged (0 keeps them forever)")
 	rootCmd.PersistentFlags().StringVar(&mountType, "mount-type", "exfat", "filesystem type for mounting")
 	rootCmd.PersistentFlags().StringVar(&immichLibrary, "library", "", "library to trigger a scan on")
 	rootCmd.PersistentFlags().StringVar(&immichKey, "key", os.Getenv("IMMICH_API_KEY"), "immich api key (env: IMMICH_API_KEY)")
 	rootCmd.PersistentFlags().StringVar(&immichServer, "server", os.Getenv("IMMICH_SERVER"), "immich api base url (env: IMMICH_SERVER)")
+	rootCmd.PersistentFlags().IntVar(&concurrency, "concurrency", 4, "number of parallel immich uploads")
+	rootCmd.PersistentFlags().IntVar(&retries, "retries", 3, "retries for failed immich requests (5xx, 429, network errors)")
+	rootCmd.PersistentFlags().DurationVar(&retryDelay, "retry-delay", time.Second, "initial delay between immich retries, doubled per attempt")
+	rootCmd.PersistentFlags().StringSliceVar(&profileNames, "profile", nil, "config profile(s) to apply, in order")
+	// Persistent like every other setting, so the config file's defaults can hold it.
+	rootCmd.PersistentFlags().DurationVar(&pollInterval, "poll-interval", 2*time.Second, "how often watch looks for newly attached cards")
 	rootCmd.PersistentFlags().SortFlags = false
 
-	cameraCmds := []struct {
-		use   string
-		short string
-		job   cameraJob
-	}{
-		{
-			use:   "sony",
-			short: "Organise Sony camera photos (default)",
-			job: cameraJob{
-				name:           "sony",
-				defaultSource:  func() string { return filepath.Join(directory, "DCIM") },
-				group:          groupSonyByDate,
-				clearSonyIndex: true,
-			},
-		},
-		{
-			use:   "sony-video",
-			short: "Transfer Sony camera videos via rsync",
-			job: cameraJob{
-				name:           "sony-video",
-				defaultSource:  func() string { return filepath.Join(directory, "PRIVATE", "M4ROOT", "CLIP") },
-				group:          groupSonyVideosByDate,
-				flatCleanup:    true,
-				clearSonyIndex: true,
-				rsyncOnly:      true,
-			},
-		},
-		{
-			use:   "dji",
-			short: "Organise DJI camera (action/drone) photos",
-			job: cameraJob{
-				name:          "dji",
-				defaultSource: func() string { return filepath.Join(directory, "DCIM", "DJI_001") },
-				group:         groupDJIByDate,
-				flatCleanup:   true,
-			},
-		},
-		{
-			use:   "canon",
-			short: "Organise Canon camera photos",
-			job: cameraJob{
-				name:          "canon",
-				defaultSource: func() string { return filepath.Join(directory, "DCIM") },
-				group:         groupCanonByDate,
-			},
-		},
-		{
-			use:   "charmera",
-			short: "Organise Kodak Charmera keychain camera photos",
-			job: cameraJob{
-				name:          "charmera",
-				defaultSource: func() string { return directory },
-				group:         groupCharmeraByDate,
-				flatCleanup:   true,
-			},
-		},
-	}
 	for _, cc := range cameraCmds {
-		cameraCmd := &cobra.Command{
-			Use:   cc.use,
-			Short: cc.short,
-			Run:   cc.job.run,
-		}
-		_ = cameraCmd.MarkPersistentFlagRequired("device")
-		_ = cameraCmd.MarkPersistentFlagRequired("directory")
-		rootCmd.AddCommand(cameraCmd)
+		rootCmd.AddCommand(newCameraCmd(cc.use, cc.short, cc.camera))
+	}
+	autoCmd := &cobra.Command{
+		Use:   "auto",
+		Short: "Detect the camera(s) from the card layout and organise their files",
+		RunE:  runAuto,
+	}
+
+	planCmd := &cobra.Command{
+		Use:   "plan [camera...]",
+		Short: "Show what a run would transfer, mounting the card read-only",
+		RunE:  runPlan,
+	}
+
+	historyCmd := &cobra.Command{
+		Use:   "history [run-id | card]",
+		Short: "List past runs, or show one run or one card's runs",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runHistory,
+	}
+
+	resumeCmd := &cobra.Command{
+		Use:   "resume",
+		Short: "Continue the last unfinished run for the card",
+		RunE:  runResume,
+	}
+
+	purgeCmd := &cobra.Command{
+		Use:   "purge",
+		Short: "Delete trash entries older than --trash-retention days",
+		RunE:  runPurge,
+	}
+
+	watchCmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Import configured cards as they are attached, without prompting",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWatch(cmd.Context(), cfg.Cards)
+		},
 	}
 
 	syncCmd := &cobra.Command{
 		Use:   "sync",
 		Short: "Trigger an immich sync",
-		Run:   runSyncCmd,
+		RunE:  runSyncCmd,
 	}
 	_ = syncCmd.MarkPersistentFlagRequired("library")
 
@@ -203,77 +334,90 @@ func main() {
 		Run:   runUpdate,
 	}
 
+	rootCmd.AddCommand(autoCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(planCmd)
+	rootCmd.AddCommand(purgeCmd)
+	rootCmd.AddCommand(resumeCmd)
 	rootCmd.AddCommand(syncCmd)
 	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(watchCmd)
 	rootCmd.AddCommand(updateCmd)
+	// Config cameras come last, so a clash with any built-in command is caught.
+	if err := addConfigCameras(rootCmd, cfg.Cameras); err != nil {
+		log.Fatal().Err(err).Msg("Invalid camera definition in config file")
+	}
 	rootCmd.Run = func(cmd *cobra.Command, args []string) {
 		_ = cmd.Help()
 	}
 
-	if err := rootCmd.Execute(); err != nil {
-		log.Fatal().Err(err).Msg("Failed to execute command")
+	if err := rootCmd.ExecuteContext(interruptContext()); err != nil {
+		log.Error().Err(err).Msg("photo-organiser failed")
+		os.Exit(exitCode(err))
 	}
 }
 
-// transferPhotos uploads or syncs a set of date groups using the configured mode.
-// Immich API mode is used when --server and --key are set; rsync is the fallback.
-func transferPhotos(groups []dateGroup) {
-	if immichServer != "" && immichKey != "" {
-		uploadByDate(groups)
-	} else if remoteHost != "" && remotePath != "" {
-		rsyncByDate(groups)
-	} else {
-		log.Fatal().Msg("provide either --server + --key (Immich API) or --host + --remote-path (rsync)")
-	}
+// interruptContext returns a context that is cancelled by the first SIGINT or
+// SIGTERM, so a run can stop starting new work, flush the upload cache, record
+// what it managed and unmount the card. A second signal kills the process.
+func interruptContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-sigs
+		signal.Stop(sigs)
+		log.Warn().Str("signal", sig.String()).Msg("interrupted: finishing up, interrupt again to quit immediately")
+		cancel()
+	}()
+	return ctx
 }
 
-// cameraJob describes how one camera subcommand locates, transfers, and cleans up files.
-type cameraJob struct {
-	name           string
-	defaultSource  func() string                     // source dir when --source is not given
-	group          func(string) ([]dateGroup, error) // group source files by date
-	flatCleanup    bool                              // remove loose files rather than whole date directories
-	clearSonyIndex bool                              // also clear Sony card index files after cleanup
-	rsyncOnly      bool                              // require rsync (no Immich upload, no library scan)
+// camerasByName holds every camera subcommand's camera by name, for resume.
+var camerasByName = make(map[string]organise.Camera)
+
+func newCameraCmd(use, short string, camera organise.Camera) *cobra.Command {
+	camerasByName[camera.Name] = camera
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return offload(cmd.Context(), camera)
+		},
+	}
+	_ = cmd.MarkPersistentFlagRequired("device")
+	_ = cmd.MarkPersistentFlagRequired("directory")
+	return cmd
 }
 
-func (job cameraJob) run(cmd *cobra.Command, args []string) {
+// offload mounts the card, runs camera's files on it through the pipeline, and
+// triggers an Immich library scan when asked to.
+func offload(ctx context.Context, camera organise.Camera) error {
 	if sourceDir == "" {
-		sourceDir = job.defaultSource()
+		sourceDir = camera.SourceDir(directory)
 		log.Debug().Str("sourceDir", sourceDir).Msg("inferred source directory")
 	}
-	if job.rsyncOnly && (remoteHost == "" || remotePath == "") {
-		log.Fatal().Msg("provide --host and --remote-path for rsync")
-	}
-
-	mountDrive()
-
-	groups, err := job.group(sourceDir)
+	transferers, err := selectBackends(camera, backends)
 	if err != nil {
-		log.Fatal().Err(err).Str("camera", job.name).Msg("failed to group files by date")
+		return fmt.Errorf("invalid backend selection: %w", err)
 	}
 
-	if job.rsyncOnly {
-		rsyncByDate(groups)
-	} else {
-		transferPhotos(groups)
+	p := newPipeline()
+	err = p.WithCard(false, func() error {
+		return p.Process(ctx, camera, sourceDir, transferers)
+	})
+	if err != nil {
+		return err
 	}
 
-	var cleaned bool
-	if job.flatCleanup {
-		cleaned = promptAndCleanupFlat()
-	} else {
-		cleaned = promptAndCleanup()
+	if usesBackend(transferers, backendImmich) && immichLibrary != "" {
+		return triggerSync(ctx)
 	}
-	if cleaned && job.clearSonyIndex {
-		cleanupSonyCardIndex(directory)
-	}
-
-	unmountDrive()
+	return nil
+}
 
-	if !job.rsyncOnly && immichKey != "" && immichServer != "" && immichLibrary != "" {
-		triggerSync()
-	}
+func rsyncConfigured() bool {
+	return remoteHost != "" && remotePath != ""
 }
 
 func runVersion(cmd *cobra.Command, args []string) {
@@ -290,59 +434,17 @@ func runVersion(cmd *cobra.Command, args []string) {
 	}
 }
 
-func runSyncCmd(cmd *cobra.Command, args []string) {
+func runSyncCmd(cmd *cobra.Command, args []string) error {
 	if immichKey == "" {
-		log.Fatal().Msg("provide --key or set IMMICH_API_KEY")
+		return fmt.Errorf("provide --key or set IMMICH_API_KEY")
 	}
 	if immichServer == "" {
-		log.Fatal().Msg("provide --server or set IMMICH_SERVER")
-	}
-	triggerSync()
-}
-
-func triggerSync() {
-	url := immichServer + "/libraries/" + immichLibrary + "/scan"
-	log.Debug().Str("url", url).Msg("Making request to server")
-	req, err := http.NewRequest("POST", url, nil)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to create http request")
+		return fmt.Errorf("provide --server or set IMMICH_SERVER")
 	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", immichKey)
-
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to perform http request")
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	bodyBytes, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode != 204 {
-		var apiErr ImmichError
-		if err := json.Unmarshal(bodyBytes, &apiErr); err == nil {
-			log.Fatal().
-				Int("status", apiErr.StatusCode).
-				Str("url", url).
-				Str("error", apiErr.Message).
-				Err(&apiErr).
-				Msg("Failed to trigger scan")
-		} else {
-			// fallback if response isn't the expected JSON
-			log.Fatal().
-				Int("status", resp.StatusCode).
-				Str("url", url).
-				Str("http_body", string(bodyBytes)).
-				Msg("Failed to trigger scan")
-		}
-	}
-
-	log.Info().Msg("sync triggered successfully")
+	return triggerSync(cmd.Context())
 }
 
-func (e *ImmichError) Error() string {
-	if e == nil {
-		return ""
-	}
-	return e.Message
+// triggerSync asks the Immich server to scan --library.
+func triggerSync(ctx context.Context) error {
+	return immichClient().ScanLibrary(ctx, immichLibrary)
 }
@@ -0,0 +1,260 @@
+// Package mount mounts and unmounts a memory card and identifies it by its
+// filesystem UUID and volume label.
+package mount
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DiskByUUID and DiskByLabel link each filesystem UUID and volume label to its
+// device node.
+var (
+	DiskByUUID  = "/dev/disk/by-uuid"
+	DiskByLabel = "/dev/disk/by-label"
+)
+
+// FSType reports the filesystem type on device, or "" if it is not known. It asks
+// lsblk, which reads udev's database and so needs no root.
+var FSType = func(device string) (string, error) {
+	out, err := exec.Command("lsblk", "-n", "-d", "-o", "FSTYPE", device).Output()
+	if err != nil {
+		return "", fmt.Errorf("lsblk %s: %w", device, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Card is a memory card's device and where it is mounted. With an empty Type the
+// card is assumed to be mounted already, and Mount and Unmount do nothing.
+type Card struct {
+	Device string    // device node, e.g. /dev/sdd1, or LABEL=<label> or UUID=<uuid>
+	Dir    string    // mount point
+	Type   string    // filesystem type passed to mount -t
+	Stdout io.Writer // output of the mount commands; os.Stdout when nil
+}
+
+func (c Card) command(name string, args ...string) *exec.Cmd {
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = c.Stdout
+	if cmd.Stdout == nil {
+		cmd.Stdout = os.Stdout
+	}
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// Mount mounts the card on its mount point, creating the directory if needed. A
+// read-only mount cannot write anything to the card, not even access times. It
+// refuses a device whose filesystem is known not to be Type.
+func (c Card) Mount(readOnly bool) error {
+	if c.Type == "" {
+		log.Info().Msg("Skipping mount step (mount-type is empty)")
+		return nil
+	}
+	dev, err := Resolve(c.Device)
+	if err != nil {
+		return err
+	}
+	if err := checkFSType(dev, c.Type); err != nil {
+		return err
+	}
+
+	// Ensure mount point exists
+	if _, err := os.Stat(c.Dir); os.IsNotExist(err) {
+		if err := os.MkdirAll(c.Dir, 0755); err != nil {
+			log.Warn().Err(err).Str("mount_point", c.Dir).Msg("Failed to create mount point directory, retrying with sudo")
+			if err := c.command("sudo", "mkdir", "-p", c.Dir).Run(); err != nil {
+				return fmt.Errorf("creating mount point %s: %w", c.Dir, err)
+			}
+			log.Info().Str("mount_point", c.Dir).Msg("Created mount point directory with sudo")
+		}
+		log.Info().Str("mount_point", c.Dir).Msg("Created mount point directory")
+	}
+
+	uid := os.Getuid()
+	gid := os.Getgid()
+	var mountOpts string
+	switch c.Type {
+	case "vfat", "exfat", "msdos", "fat":
+		mountOpts = fmt.Sprintf("uid=%d,gid=%d,umask=0022", uid, gid)
+	default:
+		mountOpts = fmt.Sprintf("uid=%d,gid=%d", uid, gid)
+	}
+	if readOnly {
+		mountOpts = "ro," + mountOpts
+	}
+
+	log.Info().Str("drive", dev).Str("mount_point", c.Dir).Str("type", c.Type).Bool("read_only", readOnly).Msg("Mounting drive")
+	if err := c.command("sudo", "mount", "-t", c.Type, dev, c.Dir, "-o", mountOpts).Run(); err != nil {
+		return fmt.Errorf("mounting %s: %w", dev, err)
+	}
+	log.Info().Msg("Drive mounted successfully.")
+	return nil
+}
+
+// Unmount unmounts the card's mount point.
+func (c Card) Unmount() error {
+	if c.Type == "" {
+		log.Info().Msg("Skipping unmount step (mount-type is empty)")
+		return nil
+	}
+	log.Info().Str("mount_point", c.Dir).Msg("Unmounting drive")
+	if err := c.command("sudo", "umount", "-R", c.Dir).Run(); err != nil {
+		return fmt.Errorf("unmounting %s: %w", c.Dir, err)
+	}
+	log.Info().Msg("Drive unmounted successfully.")
+	return nil
+}
+
+// ID identifies the card by its filesystem UUID, so it is recognised whichever
+// device node it appears as. Without one, the device path is used instead.
+func (c Card) ID() string {
+	if dev, err := Resolve(c.Device); err == nil {
+		if uuid := diskLinkName(DiskByUUID, dev); uuid != "" {
+			return uuid
+		}
+	}
+	return strings.Trim(strings.ReplaceAll(c.Device, "/", "_"), "_")
+}
+
+// Label returns the card's volume label, or "" if it has none.
+func (c Card) Label() string {
+	dev, err := Resolve(c.Device)
+	if err != nil {
+		return ""
+	}
+	return unescapeUdev(diskLinkName(DiskByLabel, dev))
+}
+
+// Resolve returns the device node for a device given as LABEL=<volume label> or
+// UUID=<filesystem UUID>, as mount and fstab accept them, by looking it up in
+// DiskByLabel or DiskByUUID. Anything else is returned unchanged.
+func Resolve(device string) (string, error) {
+	key, value, _ := strings.Cut(device, "=")
+	var dir string
+	switch key {
+	case "LABEL":
+		dir = DiskByLabel
+	case "UUID":
+		dir = DiskByUUID
+	default:
+		return device, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("resolving %s: %w", device, err)
+	}
+	for _, e := range entries {
+		name := unescapeUdev(e.Name())
+		if name != value && (key != "UUID" || !strings.EqualFold(name, value)) {
+			continue
+		}
+		dev, err := filepath.EvalSymlinks(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return "", fmt.Errorf("resolving %s: %w", device, err)
+		}
+		log.Debug().Str("device", device).Str("drive", dev).Msg("resolved device")
+		return dev, nil
+	}
+	return "", fmt.Errorf("no device with %s is attached", device)
+}
+
+// checkFSType returns an error when dev's filesystem is known and is not typ, so a
+// different disk that took the card's device node is never mounted in its place.
+// A type that cannot be told is only logged.
+func checkFSType(dev, typ string) error {
+	got, err := FSType(dev)
+	if err != nil || got == "" {
+		log.Warn().Err(err).Str("drive", dev).Msg("could not tell the filesystem type; mounting anyway")
+		return nil
+	}
+	if fsFamily(got) != fsFamily(typ) {
+		return fmt.Errorf("%s holds a %s filesystem, not %s (check --device and --mount-type)", dev, got, typ)
+	}
+	return nil
+}
+
+// fsFamily maps the names mount accepts for the same FAT driver onto the one
+// lsblk reports.
+func fsFamily(typ string) string {
+	switch typ {
+	case "fat", "msdos":
+		return "vfat"
+	}
+	return typ
+}
+
+// Disk is a block device with a filesystem, as listed under /dev/disk.
+type Disk struct {
+	Device string // device node, e.g. /dev/sdd1
+	UUID   string // filesystem UUID, or ""
+	Label  string // volume label, or ""
+}
+
+// Disks lists the attached devices that have a filesystem UUID or volume label,
+// ordered by device node.
+func Disks() []Disk {
+	disks := make(map[string]*Disk)
+	add := func(dir string, set func(d *Disk, name string)) {
+		entries, _ := os.ReadDir(dir)
+		for _, e := range entries {
+			dev, err := filepath.EvalSymlinks(filepath.Join(dir, e.Name()))
+			if err != nil {
+				continue
+			}
+			if disks[dev] == nil {
+				disks[dev] = &Disk{Device: dev}
+			}
+			set(disks[dev], e.Name())
+		}
+	}
+	add(DiskByUUID, func(d *Disk, name string) { d.UUID = name })
+	add(DiskByLabel, func(d *Disk, name string) { d.Label = unescapeUdev(name) })
+
+	list := make([]Disk, 0, len(disks))
+	for _, d := range disks {
+		list = append(list, *d)
+	}
+	slices.SortFunc(list, func(a, b Disk) int { return strings.Compare(a.Device, b.Device) })
+	return list
+}
+
+// diskLinkName returns the name of the link in dir that points at dev, or "".
+func diskLinkName(dir, dev string) string {
+	target, err := filepath.EvalSymlinks(dev)
+	if err != nil {
+		return ""
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if t, err := filepath.EvalSymlinks(filepath.Join(dir, e.Name())); err == nil && t == target {
+			return e.Name()
+		}
+	}
+	return ""
+}
+
+// unescapeUdev decodes the \xHH escapes udev uses in /dev/disk link names, such as
+// \x20 for a space in a volume label.
+func unescapeUdev(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '\\' && i+3 < len(name) && name[i+1] == 'x' {
+			if c, err := strconv.ParseUint(name[i+2:i+4], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,131 @@
+package mount
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCardID(t *testing.T) {
+	dev := filepath.Join(t.TempDir(), "sdd1")
+	if err := os.WriteFile(dev, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	DiskByUUID, DiskByLabel = t.TempDir(), t.TempDir()
+	t.Cleanup(func() { DiskByUUID, DiskByLabel = "/dev/disk/by-uuid", "/dev/disk/by-label" })
+
+	if err := os.Symlink(dev, filepath.Join(DiskByUUID, "ABCD-1234")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(dev, filepath.Join(DiskByLabel, `SONY\x20A7`)); err != nil {
+		t.Fatal(err)
+	}
+	card := Card{Device: dev}
+	if got := card.ID(); got != "ABCD-1234" {
+		t.Errorf("ID() = %q, want the filesystem UUID", got)
+	}
+	if got := card.Label(); got != "SONY A7" {
+		t.Errorf("Label() = %q, want the unescaped volume label", got)
+	}
+
+	card.Device = "/dev/sdz9"
+	if got := card.ID(); got != "dev_sdz9" {
+		t.Errorf("ID() without a UUID = %q, want dev_sdz9", got)
+	}
+}
+
+func TestDisks(t *testing.T) {
+	dir := t.TempDir()
+	sdd1, sde1 := filepath.Join(dir, "sdd1"), filepath.Join(dir, "sde1")
+	for _, dev := range []string{sdd1, sde1} {
+		if err := os.WriteFile(dev, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	DiskByUUID, DiskByLabel = t.TempDir(), t.TempDir()
+	t.Cleanup(func() { DiskByUUID, DiskByLabel = "/dev/disk/by-uuid", "/dev/disk/by-label" })
+
+	links := map[string]string{
+		filepath.Join(DiskByUUID, "ABCD-1234"):   sdd1,
+		filepath.Join(DiskByLabel, `SONY\x20A7`): sdd1,
+		filepath.Join(DiskByUUID, "5678-EF90"):   sde1,
+		filepath.Join(DiskByLabel, "dangling"):   filepath.Join(dir, "gone"),
+	}
+	for link, dev := range links {
+		if err := os.Symlink(dev, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []Disk{
+		{Device: sdd1, UUID: "ABCD-1234", Label: "SONY A7"},
+		{Device: sde1, UUID: "5678-EF90"},
+	}
+	if got := Disks(); !slices.Equal(got, want) {
+		t.Errorf("Disks() = %+v, want %+v", got, want)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	dev := filepath.Join(t.TempDir(), "sdd1")
+	if err := os.WriteFile(dev, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	DiskByUUID, DiskByLabel = t.TempDir(), t.TempDir()
+	t.Cleanup(func() { DiskByUUID, DiskByLabel = "/dev/disk/by-uuid", "/dev/disk/by-label" })
+	if err := os.Symlink(dev, filepath.Join(DiskByUUID, "ABCD-1234")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(dev, filepath.Join(DiskByLabel, `SONY\x20A7`)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		device, want string
+		ok           bool
+	}{
+		{"LABEL=SONY A7", dev, true},
+		{"UUID=ABCD-1234", dev, true},
+		{"UUID=abcd-1234", dev, true},
+		{"/dev/sdz9", "/dev/sdz9", true},
+		{"LABEL=CANON", "", false},
+	} {
+		got, err := Resolve(tc.device)
+		if got != tc.want || (err == nil) != tc.ok {
+			t.Errorf("Resolve(%q) = (%q, %v), want %q", tc.device, got, err, tc.want)
+		}
+	}
+
+	card := Card{Device: "LABEL=SONY A7"}
+	if card.ID() != "ABCD-1234" || card.Label() != "SONY A7" {
+		t.Errorf("ID(), Label() = %q, %q, want the resolved device's", card.ID(), card.Label())
+	}
+}
+
+func TestMountRefusesOtherFilesystems(t *testing.T) {
+	orig := FSType
+	t.Cleanup(func() { FSType = orig })
+	FSType = func(string) (string, error) { return "ext4", nil }
+
+	// The check comes before mount is run, so nothing is mounted.
+	err := Card{Device: "/dev/sdz9", Dir: t.TempDir(), Type: "exfat"}.Mount(true)
+	if err == nil {
+		t.Fatal("expected an ext4 device to be refused for --mount-type exfat")
+	}
+
+	for _, tc := range []struct {
+		got, typ string
+		ok       bool
+	}{
+		{"exfat", "exfat", true},
+		{"vfat", "msdos", true},
+		{"", "exfat", true}, // unknown
+		{"ntfs", "vfat", false},
+	} {
+		FSType = func(string) (string, error) { return tc.got, nil }
+		if err := checkFSType("/dev/sdz9", tc.typ); (err == nil) != tc.ok {
+			t.Errorf("checkFSType(%s on the device, %s) = %v", tc.got, tc.typ, err)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package organise
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// TrashDirName is the folder at the card root that cleaned files can be moved
+// into instead of being deleted. Grouping never descends into it.
+const TrashDirName = ".photo-organiser-trash"
+
+// Camera describes how to find and group one camera's files on a card.
+type Camera struct {
+	Name           string                                                           // e.g. "sony"
+	Source         string                                                           // default source directory, relative to the card root
+	Group          func(ctx context.Context, sourceDir string) ([]DateGroup, error) // group source files by date
+	FlatCleanup    bool                                                             // files sit directly in the source rather than in subdirectories
+	ClearSonyIndex bool                                                             // also clear Sony card index files after cleanup
+	NoImmich       bool                                                             // copy files only: rsync or local, no Immich upload or library scan
+}
+
+// SourceDir returns the camera's default source directory on the card mounted
+// at cardRoot.
+func (c Camera) SourceDir(cardRoot string) string {
+	return filepath.Join(cardRoot, c.Source)
+}
+
+// The built-in cameras.
+var (
+	Sony = Camera{
+		Name:           "sony",
+		Source:         "DCIM",
+		Group:          GroupSonyByDate,
+		ClearSonyIndex: true,
+	}
+	SonyVideo = Camera{
+		Name:           "sony-video",
+		Source:         filepath.Join("PRIVATE", "M4ROOT", "CLIP"),
+		Group:          GroupSonyVideosByDate,
+		FlatCleanup:    true,
+		ClearSonyIndex: true,
+		NoImmich:       true,
+	}
+	DJI = Camera{
+		Name:        "dji",
+		Source:      filepath.Join("DCIM", "DJI_001"),
+		Group:       GroupDJIByDate,
+		FlatCleanup: true,
+	}
+	Canon = Camera{
+		Name:   "canon",
+		Source: "DCIM",
+		Group:  GroupCanonByDate,
+	}
+	Charmera = Camera{
+		Name:        "charmera",
+		Group:       GroupCharmeraByDate,
+		FlatCleanup: true,
+	}
+)
+
+// Builtin lists the built-in cameras in the order Detect returns them.
+var Builtin = []Camera{Sony, SonyVideo, DJI, Canon, Charmera}
+
+// Definition declares a camera in the config file so it gets its own subcommand
+// without a dedicated grouping function. For example:
+//
+//	cameras:
+//	  fuji:
+//	    short: Organise Fujifilm photos
+//	    source: DCIM/100_FUJI
+//	    filename-regex: '^DSCF_(?P<year>\d{4})(?P<month>\d{2})(?P<day>\d{2})_\d+\..+$'
+//	    exif-fallback: true
+//	    flat-cleanup: true
+//	    extensions: [.jpg, .raf]
+type Definition struct {
+	Short         string   `yaml:"short"`          // subcommand description
+	Source        string   `yaml:"source"`         // default source, relative to the mount point
+	FilenameRegex string   `yaml:"filename-regex"` // captures year, month, day (named or groups 1-3)
+	EXIFFallback  bool     `yaml:"exif-fallback"`  // date unmatched files with PhotoDate
+	FlatCleanup   bool     `yaml:"flat-cleanup"`   // files sit directly in source rather than in subdirectories
+	Extensions    []string `yaml:"extensions"`     // allowed extensions; empty allows all
+}
+
+// cameraMatcher is a compiled Definition used by groupByDefinition.
+type cameraMatcher struct {
+	filename     *regexp.Regexp
+	yearIdx      int
+	monthIdx     int
+	dayIdx       int
+	exifFallback bool
+	flat         bool
+	extensions   map[string]bool
+}
+
+// Camera builds the Camera for a definition called name.
+func (def Definition) Camera(name string) (Camera, error) {
+	m, err := def.compile()
+	if err != nil {
+		return Camera{}, fmt.Errorf("camera %q: %w", name, err)
+	}
+	return Camera{
+		Name:   name,
+		Source: def.Source,
+		Group: func(ctx context.Context, sourceDir string) ([]DateGroup, error) {
+			return groupByDefinition(ctx, sourceDir, m)
+		},
+		FlatCleanup: def.FlatCleanup,
+	}, nil
+}
+
+func (def Definition) compile() (*cameraMatcher, error) {
+	m := &cameraMatcher{
+		exifFallback: def.EXIFFallback,
+		flat:         def.FlatCleanup,
+	}
+	if def.FilenameRegex == "" && !def.EXIFFallback {
+		return nil, fmt.Errorf("needs filename-regex, exif-fallback, or both")
+	}
+
+	if def.FilenameRegex != "" {
+		re, err := regexp.Compile(def.FilenameRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filename-regex: %w", err)
+		}
+		m.filename = re
+		m.yearIdx, m.monthIdx, m.dayIdx = re.SubexpIndex("year"), re.SubexpIndex("month"), re.SubexpIndex("day")
+		if m.yearIdx < 0 || m.monthIdx < 0 || m.dayIdx < 0 {
+			// Fall back to positional groups, as in djiFilenameRegex.
+			if re.NumSubexp() < 3 {
+				return nil, fmt.Errorf("filename-regex must capture year, month and day")
+			}
+			m.yearIdx, m.monthIdx, m.dayIdx = 1, 2, 3
+		}
+	}
+
+	if len(def.Extensions) > 0 {
+		m.extensions = make(map[string]bool, len(def.Extensions))
+		for _, ext := range def.Extensions {
+			ext = strings.ToLower(ext)
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			m.extensions[ext] = true
+		}
+	}
+	return m, nil
+}
+
+// fileDate dates a single file by its name, falling back to PhotoDate when
+// configured. ok is false when the file cannot be dated and should be skipped.
+func (m *cameraMatcher) fileDate(path string) (date string, ok bool) {
+	if m.filename != nil {
+		if matches := m.filename.FindStringSubmatch(filepath.Base(path)); matches != nil {
+			raw := fmt.Sprintf("%s-%s-%s", matches[m.yearIdx], matches[m.monthIdx], matches[m.dayIdx])
+			if _, err := time.Parse("2006-01-02", raw); err == nil {
+				return raw, true
+			}
+			log.Debug().Str("file", path).Str("date", raw).Msg("filename date is not a valid date")
+		}
+	}
+	if !m.exifFallback {
+		return "", false
+	}
+	taken, err := PhotoDate(path)
+	if err != nil {
+		log.Warn().Str("file", path).Err(err).Msg("skipping file: cannot determine date")
+		return "", false
+	}
+	return taken.Format("2006-01-02"), true
+}
+
+// groupByDefinition is the grouping engine behind config-declared cameras. Flat
+// cameras group the files directly inside sourceDir; other cameras group the
+// files in its subdirectories by parent directory and date, as GroupCanonByDate
+// does, since directory cleanup only removes subdirectories.
+func groupByDefinition(ctx context.Context, sourceDir string, m *cameraMatcher) ([]DateGroup, error) {
+	if m.flat {
+		byDate := make(map[string][]string)
+		entries, err := os.ReadDir(sourceDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if entry.IsDir() || !m.allowed(entry.Name()) {
+				continue
+			}
+			if date, ok := m.fileDate(filepath.Join(sourceDir, entry.Name())); ok {
+				byDate[date] = append(byDate[date], entry.Name())
+			}
+		}
+		return dateGroupsFromMap(sourceDir, byDate), nil
+	}
+
+	type key struct{ dir, date string }
+	byDirDate := make(map[key][]string)
+	err := filepath.WalkDir(sourceDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == TrashDirName {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Dir(path) == filepath.Clean(sourceDir) || !m.allowed(d.Name()) {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if date, ok := m.fileDate(path); ok {
+			k := key{dir: filepath.Dir(path), date: date}
+			byDirDate[k] = append(byDirDate[k], d.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]DateGroup, 0, len(byDirDate))
+	for k, files := range byDirDate {
+		groups = append(groups, DateGroup{
+			SourceDir: k.dir,
+			Files:     files,
+			Date:      k.date,
+		})
+	}
+	return groups, nil
+}
+
+func (m *cameraMatcher) allowed(name string) bool {
+	return m.extensions == nil || m.extensions[strings.ToLower(filepath.Ext(name))]
+}
@@ -0,0 +1,99 @@
+package organise
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDefinitionCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		def     Definition
+		wantErr bool
+	}{
+		{"named groups", Definition{FilenameRegex: `^(?P<year>\d{4})(?P<month>\d{2})(?P<day>\d{2})\.jpg$`}, false},
+		{"positional groups", Definition{FilenameRegex: `^(\d{4})(\d{2})(\d{2})\.jpg$`}, false},
+		{"exif only", Definition{EXIFFallback: true}, false},
+		{"too few groups", Definition{FilenameRegex: `^(\d{4})(\d{4})\.jpg$`}, true},
+		{"invalid regex", Definition{FilenameRegex: `(`}, true},
+		{"no way to date files", Definition{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.def.compile()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGroupByDefinitionFlat(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "IMG_20240601_0001.JPG"), "p", time.Time{})
+	writeFile(t, filepath.Join(dir, "IMG_20240601_0002.RAF"), "p", time.Time{})
+	writeFile(t, filepath.Join(dir, "IMG_20240602_0003.JPG"), "p", time.Time{})
+	// Extension not in the allowed list.
+	writeFile(t, filepath.Join(dir, "IMG_20240602_0004.THM"), "x", time.Time{})
+	// No filename date: dated by PhotoDate, which falls back to mtime.
+	writeFile(t, filepath.Join(dir, "untitled.jpg"), "p", noonUTC(2024, time.June, 3))
+	// Subdirectories are ignored for flat cameras.
+	writeFile(t, filepath.Join(dir, "sub", "IMG_20240604_0005.JPG"), "p", time.Time{})
+
+	m, err := Definition{
+		FilenameRegex: `^IMG_(?P<year>\d{4})(?P<month>\d{2})(?P<day>\d{2})_\d+\..+$`,
+		EXIFFallback:  true,
+		FlatCleanup:   true,
+		Extensions:    []string{"jpg", ".RAF"},
+	}.compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := groupByDefinition(t.Context(), dir, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := groupsByDate(groups)
+	want := map[string][]string{
+		"2024-06-01": {"IMG_20240601_0001.JPG", "IMG_20240601_0002.RAF"},
+		"2024-06-02": {"IMG_20240602_0003.JPG"},
+		"2024-06-03": {"untitled.jpg"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d groups, want %d: %v", len(got), len(want), got)
+	}
+	for date, files := range want {
+		if !equalStrings(got[date], files) {
+			t.Errorf("date %s: got %v, want %v", date, got[date], files)
+		}
+	}
+}
+
+func TestGroupByDefinitionDirectories(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "100CAM", "20240601_1.jpg"), "p", time.Time{})
+	writeFile(t, filepath.Join(dir, "101CAM", "20240601_2.jpg"), "p", time.Time{})
+	// Without exif-fallback, files the regex cannot date are skipped.
+	writeFile(t, filepath.Join(dir, "100CAM", "notes.jpg"), "x", time.Time{})
+	// Loose files in the source root are left alone in directory mode.
+	writeFile(t, filepath.Join(dir, "20240601_3.jpg"), "p", time.Time{})
+
+	m, err := Definition{FilenameRegex: `^(\d{4})(\d{2})(\d{2})_\d+\.jpg$`}.compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups, err := groupByDefinition(t.Context(), dir, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want one per directory: %+v", len(groups), groups)
+	}
+	for _, g := range groups {
+		if g.Date != "2024-06-01" || len(g.Files) != 1 {
+			t.Errorf("unexpected group %+v", g)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package organise
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// DefaultDestTemplate reproduces the original <dest>/<YYYY-MM-DD> layout.
+const DefaultDestTemplate = "{{.Date}}"
+
+// TemplateData is the data destination and album templates are rendered with.
+type TemplateData struct {
+	Date   string // YYYY-MM-DD
+	Year   string // YYYY
+	Month  string // MM
+	Day    string // DD
+	Camera string // camera subcommand, e.g. "sony"
+	Make   string // EXIF Make
+	Model  string // EXIF Model
+	Lens   string // EXIF LensModel
+}
+
+// RenderDest renders tmpl for group into a slash-separated path relative to the
+// destination root. Slashes inside field values are replaced so EXIF strings cannot
+// add directory levels, blank segments are dropped, and a path that would escape
+// the destination root is rejected.
+func RenderDest(tmpl string, group DateGroup) (string, error) {
+	if tmpl == "" {
+		tmpl = DefaultDestTemplate
+	}
+	rendered, err := RenderTemplate("dest", tmpl, group)
+	if err != nil {
+		return "", err
+	}
+
+	var segments []string
+	for _, seg := range strings.Split(rendered, "/") {
+		seg = strings.TrimSpace(seg)
+		switch seg {
+		case "", ".":
+			continue
+		case "..":
+			return "", fmt.Errorf("dest template %q escapes the destination root", tmpl)
+		}
+		segments = append(segments, seg)
+	}
+	if len(segments) == 0 {
+		return "", fmt.Errorf("dest template %q rendered an empty path", tmpl)
+	}
+	return path.Join(segments...), nil
+}
+
+// RenderTemplate executes the template tmpl, called name in errors, with
+// group's TemplateData.
+func RenderTemplate(name, tmpl string, group DateGroup) (string, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("parsing %s template: %w", name, err)
+	}
+
+	year, month, day := group.Date, "", ""
+	if parts := strings.Split(group.Date, "-"); len(parts) == 3 {
+		year, month, day = parts[0], parts[1], parts[2]
+	}
+	clean := func(s string) string { return strings.ReplaceAll(strings.TrimSpace(s), "/", "-") }
+	data := TemplateData{
+		Date:   group.Date,
+		Year:   year,
+		Month:  month,
+		Day:    day,
+		Camera: clean(group.Camera),
+		Make:   clean(group.Make),
+		Model:  clean(group.Model),
+		Lens:   clean(group.Lens),
+	}
+
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("rendering %s template: %w", name, err)
+	}
+	return b.String(), nil
+}
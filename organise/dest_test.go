@@ -0,0 +1,46 @@
+package organise
+
+import "testing"
+
+func TestRenderDest(t *testing.T) {
+	group := DateGroup{
+		Date:   "2024-06-01",
+		Camera: "sony",
+		Make:   "SONY",
+		Model:  "ILCE-7M4",
+		Lens:   "FE 24-70mm F2.8 GM II/SEL",
+	}
+	tests := []struct {
+		name    string
+		tmpl    string
+		want    string
+		wantErr bool
+	}{
+		{"default", "", "2024-06-01", false},
+		{"nested with camera", "{{.Year}}/{{.Month}}/{{.Date}} {{.Camera}}", "2024/06/2024-06-01 sony", false},
+		{"camera first", "{{.Camera}}/{{.Year}}/{{.Date}}", "sony/2024/2024-06-01", false},
+		{"slashes in values stay in one segment", "{{.Model}}/{{.Lens}}", "ILCE-7M4/FE 24-70mm F2.8 GM II-SEL", false},
+		{"blank segments dropped", "{{.Year}}//{{.Day}}/", "2024/01", false},
+		{"escape rejected", "../{{.Date}}", "", true},
+		{"empty rejected", "{{/* nothing */}}", "", true},
+		{"unknown field", "{{.Shutter}}", "", true},
+		{"bad syntax", "{{.Date", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderDest(tt.tmpl, group)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderDest(%q) = %q, want %q", tt.tmpl, got, tt.want)
+			}
+		})
+	}
+}
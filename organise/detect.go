@@ -0,0 +1,71 @@
+package organise
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Detect inspects the layout of a card mounted at cardRoot and returns the names
+// of the built-in cameras whose files it contains, in processing order. A Sony card
+// can hold both stills and clips, in which case both sony and sony-video are returned.
+func Detect(cardRoot string) []string {
+	var names []string
+
+	sonyCard := exists(filepath.Join(cardRoot, "PRIVATE", "SONY", "SONYCARD.IND")) ||
+		isDir(filepath.Join(cardRoot, "PRIVATE", "M4ROOT"))
+	if sonyCard && hasSonyDateFolders(filepath.Join(cardRoot, "DCIM")) {
+		names = append(names, Sony.Name)
+	}
+	if sonyCard && hasFiles(filepath.Join(cardRoot, "PRIVATE", "M4ROOT", "CLIP"), videoExtensions) {
+		names = append(names, SonyVideo.Name)
+	}
+	if isDir(filepath.Join(cardRoot, "DCIM", "DJI_001")) {
+		names = append(names, DJI.Name)
+	}
+	if isDir(filepath.Join(cardRoot, "DCIM", "CANONMSC")) {
+		names = append(names, Canon.Name)
+	}
+	if hasFiles(cardRoot, map[string]bool{".jpg": true, ".jpeg": true, ".avi": true}) {
+		names = append(names, Charmera.Name)
+	}
+	return names
+}
+
+// hasSonyDateFolders reports whether dcim contains any Sony-style date folder.
+func hasSonyDateFolders(dcim string) bool {
+	entries, err := os.ReadDir(dcim)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && sonyFolderNameRegex.MatchString(entry.Name()) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasFiles reports whether dir directly contains a file with one of extensions.
+func hasFiles(dir string, extensions map[string]bool) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && extensions[strings.ToLower(filepath.Ext(entry.Name()))] {
+			return true
+		}
+	}
+	return false
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,57 @@
+package organise
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string // paths relative to the card root
+		want  []string
+	}{
+		{
+			name:  "sony stills and clips",
+			files: []string{"PRIVATE/SONY/SONYCARD.IND", "DCIM/10160722/DSC00001.ARW", "PRIVATE/M4ROOT/CLIP/C0001.MP4"},
+			want:  []string{"sony", "sony-video"},
+		},
+		{
+			name:  "sony clips only",
+			files: []string{"PRIVATE/SONY/SONYCARD.IND", "PRIVATE/M4ROOT/CLIP/C0001.MP4", "PRIVATE/M4ROOT/CLIP/C0001M01.XML"},
+			want:  []string{"sony-video"},
+		},
+		{
+			name:  "dji",
+			files: []string{"DCIM/DJI_001/DJI_20230715093000_0001_D.MP4"},
+			want:  []string{"dji"},
+		},
+		{
+			name:  "canon",
+			files: []string{"DCIM/CANONMSC/M100.CTG", "DCIM/100CANON/IMG_0001.JPG"},
+			want:  []string{"canon"},
+		},
+		{
+			name:  "charmera",
+			files: []string{"PICT0001.JPG", "MOVI0001.AVI"},
+			want:  []string{"charmera"},
+		},
+		{
+			name:  "date folders without a sony marker",
+			files: []string{"DCIM/10160722/IMG_0001.JPG"},
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for _, f := range tt.files {
+				writeFile(t, filepath.Join(root, f), "x", time.Time{})
+			}
+			if got := Detect(root); !equalStrings(got, tt.want) {
+				t.Errorf("Detect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
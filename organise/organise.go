@@ -1,6 +1,9 @@
-package main
+// Package organise finds a camera card's photos and videos and groups them by the
+// date they were taken, ready for a transfer backend to send somewhere.
+package organise
 
 import (
+	"context"
 	"fmt"
 	"io/fs"
 	"os"
@@ -19,20 +22,36 @@ var isoDateRegex = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
 var videoExtensions = map[string]bool{".mp4": true, ".mov": true}
 var creationDateRegex = regexp.MustCompile(`<CreationDate value="(\d{4}-\d{2}-\d{2})`)
 
-// dateGroup holds the rsync source and file list for one date's worth of photos.
-type dateGroup struct {
-	sourceDir string   // absolute path used as the rsync source root
-	files     []string // paths relative to sourceDir; nil means sync the whole directory
-	date      string   // "YYYY-MM-DD"
+// DateGroup holds the source directory and file list for one date's worth of photos.
+type DateGroup struct {
+	SourceDir string   // absolute path used as the rsync source root
+	Files     []string // paths relative to SourceDir; nil means sync the whole directory
+	Date      string   // "YYYY-MM-DD"
+	Camera    string   // camera that produced the group, e.g. "sony"
+	Make      string   // EXIF Make, set by Annotate
+	Model     string   // EXIF Model, set by Annotate
+	Lens      string   // EXIF LensModel, set by Annotate
 }
 
-func groupSonyByDate(sourceDir string) ([]dateGroup, error) {
+// metadataProbeLimit bounds how many files Annotate opens per group looking
+// for readable EXIF, so groups of videos or unsupported RAWs stay cheap.
+const metadataProbeLimit = 5
+
+// GroupSonyByDate returns one group per Sony date folder (e.g. 10740601) in
+// sourceDir, each syncing the whole folder.
+//
+// Like every grouping function, it reads files from the card one at a time and
+// returns ctx's error between them once ctx is done.
+func GroupSonyByDate(ctx context.Context, sourceDir string) ([]DateGroup, error) {
 	entries, err := os.ReadDir(sourceDir)
 	if err != nil {
 		return nil, err
 	}
-	var groups []dateGroup
+	var groups []DateGroup
 	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		if !entry.IsDir() || !sonyFolderNameRegex.MatchString(entry.Name()) {
 			continue
 		}
@@ -42,9 +61,9 @@ func groupSonyByDate(sourceDir string) ([]dateGroup, error) {
 			log.Warn().Str("dir", entry.Name()).Err(err).Msg("skipping directory: cannot determine date")
 			continue
 		}
-		groups = append(groups, dateGroup{
-			sourceDir: dirPath,
-			date:      date,
+		groups = append(groups, DateGroup{
+			SourceDir: dirPath,
+			Date:      date,
 		})
 	}
 	return groups, nil
@@ -62,7 +81,7 @@ func sonyFolderDate(dirPath string) (string, error) {
 		if entry.IsDir() {
 			continue
 		}
-		taken, err := photoDate(filepath.Join(dirPath, entry.Name()))
+		taken, err := PhotoDate(filepath.Join(dirPath, entry.Name()))
 		if err != nil {
 			continue
 		}
@@ -71,12 +90,16 @@ func sonyFolderDate(dirPath string) (string, error) {
 	return "", fmt.Errorf("no readable files in %s", dirPath)
 }
 
-func groupDJIByDate(sourceDir string) ([]dateGroup, error) {
+// GroupDJIByDate groups the DJI files below sourceDir by the date in their names.
+func GroupDJIByDate(ctx context.Context, sourceDir string) ([]DateGroup, error) {
 	byDate := make(map[string][]string)
 	err := filepath.WalkDir(sourceDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil || d.IsDir() {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		base := filepath.Base(path)
 		matches := djiFilenameRegex.FindStringSubmatch(base)
 		if matches == nil {
@@ -97,7 +120,10 @@ func groupDJIByDate(sourceDir string) ([]dateGroup, error) {
 	return dateGroupsFromMap(sourceDir, byDate), nil
 }
 
-func groupCanonByDate(sourceDir string) ([]dateGroup, error) {
+// GroupCanonByDate groups the photos below sourceDir by parent directory and the
+// date they were taken, skipping the camera's CANONMSC catalogue and folders
+// already named after a date.
+func GroupCanonByDate(ctx context.Context, sourceDir string) ([]DateGroup, error) {
 	type key struct{ dir, date string }
 	byDirDate := make(map[key][]string)
 
@@ -105,6 +131,9 @@ func groupCanonByDate(sourceDir string) ([]dateGroup, error) {
 		if err != nil || path == sourceDir {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		rel, err := filepath.Rel(sourceDir, path)
 		if err != nil {
 			return err
@@ -127,7 +156,7 @@ func groupCanonByDate(sourceDir string) ([]dateGroup, error) {
 			return nil
 		}
 
-		taken, err := photoDate(path)
+		taken, err := PhotoDate(path)
 		if err != nil {
 			log.Warn().Str("file", path).Err(err).Msg("skipping file: cannot determine date")
 			return nil
@@ -142,24 +171,29 @@ func groupCanonByDate(sourceDir string) ([]dateGroup, error) {
 		return nil, err
 	}
 
-	groups := make([]dateGroup, 0, len(byDirDate))
+	groups := make([]DateGroup, 0, len(byDirDate))
 	for k, files := range byDirDate {
-		groups = append(groups, dateGroup{
-			sourceDir: k.dir,
-			files:     files,
-			date:      k.date,
+		groups = append(groups, DateGroup{
+			SourceDir: k.dir,
+			Files:     files,
+			Date:      k.date,
 		})
 	}
 	return groups, nil
 }
 
-func groupCharmeraByDate(sourceDir string) ([]dateGroup, error) {
+// GroupCharmeraByDate groups the JPEG photos and AVI clips directly in sourceDir
+// by date, from EXIF for photos and mtime for clips.
+func GroupCharmeraByDate(ctx context.Context, sourceDir string) ([]DateGroup, error) {
 	byDate := make(map[string][]string)
 	entries, err := os.ReadDir(sourceDir)
 	if err != nil {
 		return nil, err
 	}
 	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		if entry.IsDir() {
 			continue
 		}
@@ -177,7 +211,7 @@ func groupCharmeraByDate(sourceDir string) ([]dateGroup, error) {
 			}
 			taken = info.ModTime()
 		} else {
-			taken, err = photoDate(filepath.Join(sourceDir, name))
+			taken, err = PhotoDate(filepath.Join(sourceDir, name))
 			if err != nil {
 				log.Warn().Str("file", name).Err(err).Msg("falling back to mtime for date")
 				info, statErr := entry.Info()
@@ -194,8 +228,8 @@ func groupCharmeraByDate(sourceDir string) ([]dateGroup, error) {
 	return dateGroupsFromMap(sourceDir, byDate), nil
 }
 
-// photoDate returns the date a photo was taken, preferring EXIF over mtime.
-func photoDate(path string) (time.Time, error) {
+// PhotoDate returns the date a photo was taken, preferring EXIF over mtime.
+func PhotoDate(path string) (time.Time, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return time.Time{}, err
@@ -222,6 +256,61 @@ func photoDate(path string) (time.Time, error) {
 	return info.ModTime(), nil
 }
 
+// Annotate records the camera name and the EXIF make, model and lens of each
+// group's first readable photo, for use in destination templates. It stops
+// probing once ctx is done, leaving the remaining groups without metadata.
+func Annotate(ctx context.Context, groups []DateGroup, camera string) {
+	for i := range groups {
+		groups[i].Camera = camera
+		if ctx.Err() != nil {
+			continue
+		}
+		files, err := groups[i].ListFiles()
+		if err != nil {
+			log.Debug().Err(err).Str("dir", groups[i].SourceDir).Msg("cannot list group for metadata")
+			continue
+		}
+		for j, rel := range files {
+			if j == metadataProbeLimit {
+				break
+			}
+			maker, model, lens, err := PhotoMetadata(filepath.Join(groups[i].SourceDir, rel))
+			if err != nil {
+				continue
+			}
+			groups[i].Make, groups[i].Model, groups[i].Lens = maker, model, lens
+			break
+		}
+	}
+}
+
+// PhotoMetadata returns the camera make, model and lens recorded in a photo's EXIF.
+// Missing individual tags are returned as empty strings.
+func PhotoMetadata(path string) (maker, model, lens string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", "", "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	x, err := exif.Decode(f)
+	if err != nil {
+		return "", "", "", err
+	}
+	field := func(name exif.FieldName) string {
+		tag, err := x.Get(name)
+		if err != nil {
+			return ""
+		}
+		val, err := tag.StringVal()
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(strings.TrimRight(val, "\x00"))
+	}
+	return field(exif.Make), field(exif.Model), field(exif.LensModel), nil
+}
+
 // parseEXIFDate handles both the standard EXIF format ("2006:01:02 15:04:05") and
 // the all-colon variant some cameras write ("2006:01:02:15:04:05").
 func parseEXIFDate(raw string) (time.Time, error) {
@@ -235,7 +324,9 @@ func parseEXIFDate(raw string) (time.Time, error) {
 // Capture group 1 is the clip base name (C0023).
 var sonyVideoSidecarRegex = regexp.MustCompile(`(?i)^([A-Z]\d+)M\d+\.XML$`)
 
-func groupSonyVideosByDate(sourceDir string) ([]dateGroup, error) {
+// GroupSonyVideosByDate groups Sony clips in sourceDir by recording date, keeping
+// each clip's XML sidecars in the same group.
+func GroupSonyVideosByDate(ctx context.Context, sourceDir string) ([]DateGroup, error) {
 	entries, err := os.ReadDir(sourceDir)
 	if err != nil {
 		return nil, err
@@ -248,6 +339,9 @@ func groupSonyVideosByDate(sourceDir string) ([]dateGroup, error) {
 
 	// First pass: video files — establish the date for each clip.
 	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		if entry.IsDir() {
 			continue
 		}
@@ -318,14 +412,38 @@ func parseSonyXMLDate(xmlPath string) (string, error) {
 	return string(sub[1]), nil
 }
 
-func dateGroupsFromMap(sourceDir string, byDate map[string][]string) []dateGroup {
-	groups := make([]dateGroup, 0, len(byDate))
+func dateGroupsFromMap(sourceDir string, byDate map[string][]string) []DateGroup {
+	groups := make([]DateGroup, 0, len(byDate))
 	for date, files := range byDate {
-		groups = append(groups, dateGroup{
-			sourceDir: sourceDir,
-			files:     files,
-			date:      date,
+		groups = append(groups, DateGroup{
+			SourceDir: sourceDir,
+			Files:     files,
+			Date:      date,
 		})
 	}
 	return groups
 }
+
+// ListFiles returns the files the group covers, relative to its SourceDir. A
+// group that syncs its whole directory is expanded to every file below it.
+func (group DateGroup) ListFiles() ([]string, error) {
+	if group.Files != nil {
+		return group.Files, nil
+	}
+	var files []string
+	err := filepath.WalkDir(group.SourceDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(group.SourceDir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
@@ -1,6 +1,8 @@
-package main
+package organise
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"sort"
@@ -29,17 +31,17 @@ func writeFile(t *testing.T, path, content string, modTime time.Time) {
 	}
 }
 
-// groupsByDate flattens a []dateGroup into date → sorted file names for
+// groupsByDate flattens a []DateGroup into date → sorted file names for
 // order-independent comparison. Groups that sync a whole directory (files == nil)
 // record a single "*" marker.
-func groupsByDate(groups []dateGroup) map[string][]string {
+func groupsByDate(groups []DateGroup) map[string][]string {
 	out := make(map[string][]string)
 	for _, g := range groups {
-		if g.files == nil {
-			out[g.date] = append(out[g.date], "*")
+		if g.Files == nil {
+			out[g.Date] = append(out[g.Date], "*")
 			continue
 		}
-		out[g.date] = append(out[g.date], g.files...)
+		out[g.Date] = append(out[g.Date], g.Files...)
 	}
 	for _, files := range out {
 		sort.Strings(files)
@@ -175,7 +177,7 @@ func TestGroupSonyVideosByDate(t *testing.T) {
 	// A non-video, non-sidecar file must be ignored.
 	writeFile(t, filepath.Join(dir, "MEDIAPRO.XML"), "junk", time.Time{})
 
-	groups, err := groupSonyVideosByDate(dir)
+	groups, err := GroupSonyVideosByDate(t.Context(), dir)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -202,7 +204,7 @@ func TestGroupDJIByDate(t *testing.T) {
 	writeFile(t, filepath.Join(dir, "DJI_20230820120000_0003_D.JPG"), "p", time.Time{})
 	writeFile(t, filepath.Join(dir, "notes.txt"), "ignored", time.Time{})
 
-	groups, err := groupDJIByDate(dir)
+	groups, err := GroupDJIByDate(t.Context(), dir)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -223,7 +225,7 @@ func TestGroupDJIByDate(t *testing.T) {
 
 func TestSonyFolderDate(t *testing.T) {
 	dir := t.TempDir()
-	// photoDate falls back to mtime for non-EXIF files.
+	// PhotoDate falls back to mtime for non-EXIF files.
 	writeFile(t, filepath.Join(dir, "DSC00001.ARW"), "raw", noonUTC(2025, time.March, 3))
 	if got, err := sonyFolderDate(dir); err != nil || got != "2025-03-03" {
 		t.Errorf("sonyFolderDate = (%q, %v), want (2025-03-03, nil)", got, err)
@@ -242,21 +244,21 @@ func TestGroupSonyByDate(t *testing.T) {
 	// A folder that does not match the 8-digit pattern is ignored.
 	writeFile(t, filepath.Join(dir, "MISC", "readme.txt"), "x", noonUTC(2026, time.July, 22))
 
-	groups, err := groupSonyByDate(dir)
+	groups, err := GroupSonyByDate(t.Context(), dir)
 	if err != nil {
 		t.Fatal(err)
 	}
 	if len(groups) != 1 {
 		t.Fatalf("got %d groups, want 1: %+v", len(groups), groups)
 	}
-	if groups[0].date != "2026-07-22" {
-		t.Errorf("date = %s, want 2026-07-22", groups[0].date)
+	if groups[0].Date != "2026-07-22" {
+		t.Errorf("date = %s, want 2026-07-22", groups[0].Date)
 	}
-	if groups[0].files != nil {
-		t.Errorf("Sony folder groups should sync the whole directory (files == nil), got %v", groups[0].files)
+	if groups[0].Files != nil {
+		t.Errorf("Sony folder groups should sync the whole directory (files == nil), got %v", groups[0].Files)
 	}
-	if filepath.Base(groups[0].sourceDir) != "10160722" {
-		t.Errorf("sourceDir = %s, want it to end in 10160722", groups[0].sourceDir)
+	if filepath.Base(groups[0].SourceDir) != "10160722" {
+		t.Errorf("sourceDir = %s, want it to end in 10160722", groups[0].SourceDir)
 	}
 }
 
@@ -269,7 +271,7 @@ func TestGroupCharmeraByDate(t *testing.T) {
 	// Subdirectories are skipped.
 	writeFile(t, filepath.Join(dir, "sub", "img3.jpg"), "p", noonUTC(2025, time.February, 3))
 
-	groups, err := groupCharmeraByDate(dir)
+	groups, err := GroupCharmeraByDate(t.Context(), dir)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -298,7 +300,7 @@ func TestGroupCanonByDate(t *testing.T) {
 	// An already-organised ISO-date directory must be skipped entirely.
 	writeFile(t, filepath.Join(dir, "2020-01-01", "IMG_9999.JPG"), "p", noonUTC(2020, time.January, 1))
 
-	groups, err := groupCanonByDate(dir)
+	groups, err := GroupCanonByDate(t.Context(), dir)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -316,6 +318,23 @@ func TestGroupCanonByDate(t *testing.T) {
 	}
 }
 
+func TestGroupingStopsWhenCancelled(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "100CANON", "IMG_0001.JPG"), "p", noonUTC(2025, time.April, 10))
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	for name, group := range map[string]func(context.Context, string) ([]DateGroup, error){
+		"canon":    GroupCanonByDate,
+		"charmera": GroupCharmeraByDate,
+		"sony":     GroupSonyByDate,
+	} {
+		if groups, err := group(ctx, dir); !errors.Is(err, context.Canceled) || groups != nil {
+			t.Errorf("%s: got (%v, %v), want no groups and context.Canceled", name, groups, err)
+		}
+	}
+}
+
 func equalStrings(a, b []string) bool {
 	if len(a) != len(b) {
 		return false
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+
+	"github.com/DistroByte/photo-organiser/mount"
+	"github.com/DistroByte/photo-organiser/pipeline"
+	"github.com/DistroByte/photo-organiser/transfer"
+)
+
+// card returns the card described by --device, --directory and --mount-type.
+func card() mount.Card {
+	return mount.Card{Device: device, Dir: directory, Type: mountType, Stdout: textOut()}
+}
+
+// newPipeline returns a pipeline for the card, configured by the flags. Each run
+// is emitted as a summary event once it is recorded in the history.
+func newPipeline() *pipeline.Pipeline {
+	return &pipeline.Pipeline{
+		Card:              card(),
+		DryRun:            dryRun,
+		Verify:            verify,
+		Cleanup:           cleanupPolicy,
+		CleanupUnattended: cleanupUnattended,
+		Unattended:        unattended || !stdinIsTerminal(),
+		Trash:             trashDir(),
+		TrashRetention:    trashRetention,
+		Prompt:            os.Stdin,
+		Out:               textOut(),
+		OnRun: func(rec pipeline.Record) {
+			emit(event{Event: transfer.Event{Name: eventSummary, Camera: rec.Camera}, Run: &rec})
+		},
+	}
+}
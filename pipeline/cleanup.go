@@ -0,0 +1,169 @@
+package pipeline
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/DistroByte/photo-organiser/organise"
+	"github.com/rs/zerolog/log"
+)
+
+// Cleanup policies for Pipeline.Cleanup and Pipeline.CleanupUnattended.
+const (
+	CleanupAsk      = "ask"      // prompt on the terminal
+	CleanupAlways   = "always"   // delete without asking
+	CleanupNever    = "never"    // leave the source untouched
+	CleanupVerified = "verified" // delete without asking once a verification pass succeeds
+)
+
+// CleanupPolicies lists every cleanup policy.
+var CleanupPolicies = []string{CleanupAsk, CleanupAlways, CleanupNever, CleanupVerified}
+
+// effectiveCleanupPolicy resolves Cleanup for this run: "ask" cannot prompt when
+// unattended, so it falls back to CleanupUnattended.
+func (p *Pipeline) effectiveCleanupPolicy() string {
+	if p.Cleanup == CleanupAsk && p.Unattended {
+		log.Info().Str("policy", p.CleanupUnattended).Msg("no one to prompt; using the unattended cleanup policy")
+		return p.CleanupUnattended
+	}
+	return p.Cleanup
+}
+
+// cleanupFiles removes exactly the given files, or moves them into the trash
+// entry directory when trash is set, then removes any directories below sourceDir
+// that this left empty. sourceDir itself is always kept.
+func (p *Pipeline) cleanupFiles(sourceDir string, files []string, trash string) (removed []string) {
+	root := filepath.Clean(sourceDir)
+	for _, path := range files {
+		var err error
+		if trash != "" {
+			log.Debug().Str("file", path).Str("trash", trash).Msg("moving file to trash during cleanup")
+			err = moveToTrash(trash, p.Card.Dir, sourceDir, path)
+		} else {
+			log.Debug().Str("file", path).Msg("removing file during cleanup")
+			err = os.Remove(path)
+		}
+		if err != nil {
+			log.Warn().Str("file", path).Err(err).Msg("failed to remove file during cleanup")
+			continue
+		}
+		removed = append(removed, path)
+		// os.Remove refuses non-empty directories, so this stops at the first
+		// directory that still holds something.
+		for dir := filepath.Dir(path); dir != root && len(dir) > len(root); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+			log.Debug().Str("dir", dir).Msg("removed empty directory during cleanup")
+		}
+	}
+	return removed
+}
+
+// leftoverFiles lists the files a cleanup of sourceDir leaves behind: the
+// top-level files for flat layouts, and everything in subdirectories otherwise.
+func leftoverFiles(sourceDir string, flat bool) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(sourceDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if (flat && path != sourceDir) || d.Name() == organise.TrashDirName {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !flat && filepath.Dir(path) == filepath.Clean(sourceDir) {
+			return nil
+		}
+		files = append(files, path)
+		return nil
+	})
+	return files, err
+}
+
+// promptAndCleanup deletes the files every backend transferred according to
+// policy, prompting first under "ask", then reports anything left on the card.
+// It returns the files it removed, and an error if any could not be. Interrupting
+// the prompt declines it and returns ctx's error.
+func (p *Pipeline) promptAndCleanup(ctx context.Context, sourceDir string, transferred []string, flat bool, policy string) ([]string, error) {
+	if p.DryRun {
+		log.Info().Msg("Dry run complete. No files were actually moved or deleted.")
+		return nil, nil
+	}
+	if len(transferred) == 0 {
+		log.Info().Msg("Nothing was transferred; skipping cleanup.")
+		return nil, nil
+	}
+	switch policy {
+	case CleanupNever:
+		log.Info().Msg("Skipping cleanup of source files (--cleanup=never).")
+		return nil, nil
+	case CleanupAsk:
+		_, _ = fmt.Fprintf(p.out(), "Delete %d transferred file(s) from the source? [y/N]: ", len(transferred))
+		answer := make(chan string, 1)
+		go func() {
+			input, _ := bufio.NewReader(p.prompt()).ReadString('\n')
+			answer <- input
+		}()
+		var input string
+		select {
+		case input = <-answer:
+		case <-ctx.Done():
+			_, _ = fmt.Fprintln(p.out())
+			log.Info().Msg("Skipping cleanup of source files.")
+			return nil, ctx.Err()
+		}
+		if len(input) == 0 || (input[0] != 'y' && input[0] != 'Y') {
+			log.Info().Msg("Skipping cleanup of source files.")
+			return nil, nil
+		}
+	}
+
+	var entry string
+	if p.Trash != "" {
+		entry = filepath.Join(p.Trash, time.Now().Format(trashEntryLayout))
+	}
+	removed := p.cleanupFiles(sourceDir, transferred, entry)
+	if entry != "" {
+		log.Info().Int("trashed", len(removed)).Str("trash", entry).Msg("Source files moved to trash.")
+		if _, err := p.PurgeTrash(time.Now()); err != nil {
+			log.Warn().Err(err).Msg("failed to purge old trash entries")
+		}
+	} else {
+		log.Info().Int("removed", len(removed)).Msg("Source files cleaned up.")
+	}
+
+	left, err := leftoverFiles(sourceDir, flat)
+	if err != nil {
+		log.Warn().Err(err).Str("dir", sourceDir).Msg("failed to list files left on the source")
+	} else if len(left) > 0 {
+		log.Warn().Int("count", len(left)).Strs("files", left).Msg("files left on the source: skipped or not transferred")
+	}
+	if failed := len(transferred) - len(removed); failed > 0 {
+		return removed, fmt.Errorf("%d file(s) could not be removed", failed)
+	}
+	return removed, nil
+}
+
+// cleanupSonyCardIndex removes the Sony card ownership index and the auto-image
+// index directory so the camera does not complain about missing files on reinsertion.
+func cleanupSonyCardIndex(cardRoot string) {
+	targets := []string{
+		filepath.Join(cardRoot, "PRIVATE", "SONY", "SONYCARD.IND"),
+		filepath.Join(cardRoot, "AVF_INFO"),
+	}
+	for _, target := range targets {
+		log.Debug().Str("path", target).Msg("removing Sony card index entry")
+		if err := os.RemoveAll(target); err != nil {
+			log.Warn().Str("path", target).Err(err).Msg("failed to remove Sony card index entry")
+		}
+	}
+	log.Info().Msg("Sony card index cleared.")
+}
@@ -0,0 +1,141 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !modTime.IsZero() {
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCleanupFilesOnlyRemovesTransferred(t *testing.T) {
+	src := t.TempDir()
+	done := filepath.Join(src, "100MSDCF", "DSC00001.ARW")
+	kept := filepath.Join(src, "101MSDCF", "DSC00002.ARW")
+	emptied := filepath.Join(src, "102MSDCF", "DSC00003.ARW")
+	skipped := filepath.Join(src, "NOTDATED", "DSC00004.ARW")
+	for _, path := range []string{done, kept, emptied, skipped} {
+		writeFile(t, path, filepath.Base(path), time.Time{})
+	}
+	writeFile(t, filepath.Join(src, "100MSDCF", "DSC00005.ARW"), "failed", time.Time{})
+
+	if removed := (&Pipeline{}).cleanupFiles(src, []string{done, emptied}, ""); !equalStrings(removed, []string{done, emptied}) {
+		t.Errorf("removed %v, want %v", removed, []string{done, emptied})
+	}
+	for _, path := range []string{done, emptied, filepath.Dir(emptied)} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s should have been removed", path)
+		}
+	}
+	if _, err := os.Stat(src); err != nil {
+		t.Errorf("source directory must be kept: %v", err)
+	}
+
+	left, err := leftoverFiles(src, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(src, "100MSDCF", "DSC00005.ARW"),
+		kept,
+		skipped,
+	}
+	if !equalStrings(left, want) {
+		t.Errorf("leftoverFiles() = %v, want %v", left, want)
+	}
+}
+
+func TestLeftoverFilesFlat(t *testing.T) {
+	src := t.TempDir()
+	writeFile(t, filepath.Join(src, "IMG_0001.JPG"), "p", time.Time{})
+	writeFile(t, filepath.Join(src, "MISC", "SETTINGS.DAT"), "s", time.Time{})
+
+	left, err := leftoverFiles(src, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalStrings(left, []string{filepath.Join(src, "IMG_0001.JPG")}) {
+		t.Errorf("leftoverFiles() = %v, want only the top-level file", left)
+	}
+}
+
+func TestEffectiveCleanupPolicy(t *testing.T) {
+	p := &Pipeline{Cleanup: CleanupAsk, CleanupUnattended: CleanupVerified}
+	if got := p.effectiveCleanupPolicy(); got != CleanupAsk {
+		t.Errorf("attended = %q, want ask", got)
+	}
+	p.Unattended = true
+	if got := p.effectiveCleanupPolicy(); got != CleanupVerified {
+		t.Errorf("unattended = %q, want the unattended policy", got)
+	}
+	p.Cleanup = CleanupAlways
+	if got := p.effectiveCleanupPolicy(); got != CleanupAlways {
+		t.Errorf("explicit policy = %q, want always", got)
+	}
+}
+
+func TestPromptAndCleanupPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		policy  string
+		removed bool
+	}{
+		{CleanupNever, false},
+		{CleanupAlways, true},
+		{CleanupVerified, true},
+	} {
+		src := t.TempDir()
+		path := filepath.Join(src, "100MSDCF", "DSC00001.ARW")
+		writeFile(t, path, "raw", time.Time{})
+		got, err := (&Pipeline{}).promptAndCleanup(t.Context(), src, []string{path}, false, tc.policy)
+		if err != nil {
+			t.Errorf("policy %q: %v", tc.policy, err)
+		}
+		if (len(got) == 1) != tc.removed {
+			t.Errorf("policy %q: promptAndCleanup() = %v, want removed=%v", tc.policy, got, tc.removed)
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) != tc.removed {
+			t.Errorf("policy %q: file removed = %v, want %v", tc.policy, os.IsNotExist(err), tc.removed)
+		}
+	}
+}
+
+func TestPromptAndCleanupReportsFailures(t *testing.T) {
+	src := t.TempDir()
+	path := filepath.Join(src, "100MSDCF", "DSC00001.ARW")
+	writeFile(t, path, "raw", time.Time{})
+	gone := filepath.Join(src, "100MSDCF", "DSC00002.ARW")
+
+	removed, err := (&Pipeline{}).promptAndCleanup(t.Context(), src, []string{path, gone}, false, CleanupAlways)
+	if !equalStrings(removed, []string{path}) {
+		t.Errorf("removed %v, want %v", removed, []string{path})
+	}
+	if err == nil {
+		t.Error("expected an error for the file that could not be removed")
+	}
+}
@@ -0,0 +1,36 @@
+package pipeline
+
+import "fmt"
+
+// Stage is the part of a run an Error came from.
+type Stage string
+
+const (
+	StageMount    Stage = "mount"
+	StageGroup    Stage = "group"
+	StageTransfer Stage = "transfer"
+	StageVerify   Stage = "verify"
+	StageCleanup  Stage = "cleanup"
+)
+
+// Error wraps an error with the stage of the run it stopped or failed.
+type Error struct {
+	Stage Stage
+	Err   error
+}
+
+// StageError wraps err with stage, returning nil for a nil err.
+func StageError(stage Stage, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Stage: stage, Err: err}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %v", e.Stage, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,115 @@
+package pipeline
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Record summarises one camera's run on one card. Records are appended to a
+// JSON-lines file in the state directory.
+type Record struct {
+	ID              string    `json:"id"`
+	Started         time.Time `json:"started"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Camera          string    `json:"camera"`
+	Card            string    `json:"card"`
+	Label           string    `json:"label,omitempty"`
+	Source          string    `json:"source"`
+	Backends        []string  `json:"backends"`
+	Resumed         bool      `json:"resumed,omitempty"`
+	Groups          int       `json:"groups"`
+	Files           int       `json:"files"`
+	Transferred     int       `json:"transferred"` // files delivered by every backend in this run
+	Bytes           int64     `json:"bytes"`       // size of the transferred files
+	Cleaned         int       `json:"cleaned"`     // files removed from the card in this run
+	Failed          int       `json:"failed"`      // files still not delivered at the end of the run
+	VerifyFailed    bool      `json:"verify_failed,omitempty"`
+	Interrupted     bool      `json:"interrupted,omitempty"` // stopped by Ctrl-C or SIGTERM
+}
+
+// Status summarises how the run ended: ok, incomplete, verify failed or
+// interrupted.
+func (r Record) Status() string {
+	switch {
+	case r.Interrupted:
+		return "interrupted"
+	case r.VerifyFailed:
+		return "verify failed"
+	case r.Failed > 0:
+		return "incomplete"
+	}
+	return "ok"
+}
+
+func (p *Pipeline) historyPath() string {
+	return filepath.Join(p.stateDir(), "history.jsonl")
+}
+
+// recordRun appends rec to the history. Dry runs are not recorded, and a failure
+// to write is logged rather than failing the run.
+func (p *Pipeline) recordRun(rec Record) {
+	if p.DryRun {
+		return
+	}
+	path := p.historyPath()
+	line, err := json.Marshal(rec)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+	}
+	if err == nil {
+		var f *os.File
+		if f, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err == nil {
+			_, err = f.Write(append(line, '\n'))
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("could not record run history")
+	}
+}
+
+// History reads every recorded run, oldest first. Unparseable lines, such as one
+// cut short by a crash, are skipped.
+func (p *Pipeline) History() ([]Record, error) {
+	return loadHistory(p.historyPath())
+}
+
+func loadHistory(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			log.Debug().Err(err).Msg("skipping unreadable history line")
+			continue
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
+
+// totalSize sums the sizes of the files at paths, skipping any it cannot stat.
+func totalSize(paths []string) int64 {
+	var total int64
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			total += info.Size()
+		}
+	}
+	return total
+}
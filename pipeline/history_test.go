@@ -0,0 +1,46 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordAndLoadHistory(t *testing.T) {
+	p := &Pipeline{StateDir: t.TempDir()}
+
+	started := time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+	p.recordRun(Record{ID: "20240601-120000-sony", Started: started, Camera: "sony", Card: "ABCD-1234", Files: 3, Transferred: 3})
+	// A line cut short by a crash must not hide the runs around it.
+	f, err := os.OpenFile(p.historyPath(), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"id": "20240602-`)
+	_, _ = f.WriteString("\n")
+	_ = f.Close()
+	p.recordRun(Record{ID: "20240603-090000-dji", Camera: "dji", Card: "EF01-2345", Failed: 1})
+
+	dry := *p
+	dry.DryRun = true
+	dry.recordRun(Record{ID: "dry"})
+
+	records, err := p.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].ID != "20240601-120000-sony" || records[1].ID != "20240603-090000-dji" {
+		t.Fatalf("History() = %+v", records)
+	}
+	if !records[0].Started.Equal(started) || records[0].Transferred != 3 {
+		t.Errorf("first record did not round-trip: %+v", records[0])
+	}
+	if records[0].Status() != "ok" || records[1].Status() != "incomplete" {
+		t.Errorf("statuses = %q, %q", records[0].Status(), records[1].Status())
+	}
+
+	if records, err := loadHistory(filepath.Join(t.TempDir(), "missing.jsonl")); err != nil || records != nil {
+		t.Errorf("missing history = (%v, %v), want empty", records, err)
+	}
+}
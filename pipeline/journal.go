@@ -0,0 +1,239 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DistroByte/photo-organiser/organise"
+	"github.com/DistroByte/photo-organiser/transfer"
+	"github.com/rs/zerolog/log"
+)
+
+// journalVersion is the current on-disk journal format.
+const journalVersion = 1
+
+// File states recorded in the run journal, in the order a file moves through them.
+const (
+	statePending     = "pending"     // found on the card, not yet delivered by every backend
+	stateTransferred = "transferred" // delivered by every backend
+	stateVerified    = "verified"    // confirmed intact by a verification pass
+	stateCleaned     = "cleaned"     // removed from the card (or moved to the trash)
+)
+
+// Journal records the state of every file in one camera's run on one card, so
+// an interrupted run can be picked up by Resume. A new run for the same card and
+// camera replaces the previous journal.
+type Journal struct {
+	Version  int            `json:"version"`
+	Card     string         `json:"card"`
+	Camera   string         `json:"camera"`
+	Source   string         `json:"source"`
+	Backends []string       `json:"backends"`
+	Started  time.Time      `json:"started"`
+	Complete bool           `json:"complete"`
+	Groups   []journalGroup `json:"groups"`
+
+	path    string // empty for a dry run, which is never persisted
+	resumed bool   // continued by Resume rather than started afresh
+}
+
+// journalGroup is one date group and the state of each of its files.
+type journalGroup struct {
+	Date      string            `json:"date"`
+	SourceDir string            `json:"source_dir"`
+	Camera    string            `json:"camera,omitempty"`
+	Make      string            `json:"make,omitempty"`
+	Model     string            `json:"model,omitempty"`
+	Lens      string            `json:"lens,omitempty"`
+	Files     map[string]string `json:"files"` // path relative to SourceDir → file state
+}
+
+// journalDir holds one directory of journals per card in the state directory.
+func (p *Pipeline) journalDir(card string) string {
+	return filepath.Join(p.stateDir(), "journal", card)
+}
+
+// newJournal starts a journal for camera's groups on the card, with every file
+// pending, and persists it.
+func (p *Pipeline) newJournal(camera, source string, transferers []transfer.Transferer, groups []organise.DateGroup) (*Journal, error) {
+	card := p.Card.ID()
+	j := &Journal{
+		Version: journalVersion,
+		Card:    card,
+		Camera:  camera,
+		Source:  source,
+		Started: time.Now(),
+	}
+	for _, t := range transferers {
+		j.Backends = append(j.Backends, t.Name())
+	}
+	for _, group := range groups {
+		files, err := group.ListFiles()
+		if err != nil {
+			return nil, fmt.Errorf("listing %s: %w", group.SourceDir, err)
+		}
+		jg := journalGroup{
+			Date:      group.Date,
+			SourceDir: group.SourceDir,
+			Camera:    group.Camera,
+			Make:      group.Make,
+			Model:     group.Model,
+			Lens:      group.Lens,
+			Files:     make(map[string]string, len(files)),
+		}
+		for _, rel := range files {
+			jg.Files[rel] = statePending
+		}
+		j.Groups = append(j.Groups, jg)
+	}
+
+	if !p.DryRun {
+		j.path = filepath.Join(p.journalDir(card), camera+".json")
+		if old, err := readJournal(j.path); err == nil && !old.Complete {
+			log.Warn().Str("camera", camera).Time("started", old.Started).Msg("replacing an unfinished run; use resume to continue one instead")
+		}
+	}
+	j.save()
+	return j, nil
+}
+
+func readJournal(path string) (*Journal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var j Journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+	if j.Version != journalVersion {
+		return nil, fmt.Errorf("unsupported journal version %d", j.Version)
+	}
+	j.path = path
+	return &j, nil
+}
+
+// IncompleteJournals returns the card's unfinished journals, oldest first.
+func (p *Pipeline) IncompleteJournals() ([]*Journal, error) {
+	dir := p.journalDir(p.Card.ID())
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var journals []*Journal
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		j, err := readJournal(filepath.Join(dir, e.Name()))
+		if err != nil {
+			log.Warn().Err(err).Str("journal", e.Name()).Msg("skipping unreadable journal")
+			continue
+		}
+		if !j.Complete {
+			journals = append(journals, j)
+		}
+	}
+	sort.Slice(journals, func(a, b int) bool { return journals[a].Started.Before(journals[b].Started) })
+	return journals, nil
+}
+
+// save writes the journal atomically. The journal only speeds up a resume, so a
+// failure is logged rather than stopping the run.
+func (j *Journal) save() {
+	if j.path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(j.path), 0755)
+	}
+	if err == nil {
+		tmp := j.path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0644); err == nil {
+			err = os.Rename(tmp, j.path)
+		}
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("path", j.path).Msg("could not save run journal")
+	}
+}
+
+// dateGroups returns the journal's groups restricted to files in one of states,
+// leaving out groups with none.
+func (j *Journal) dateGroups(states ...string) []organise.DateGroup {
+	var groups []organise.DateGroup
+	for _, jg := range j.Groups {
+		var files []string
+		for rel, state := range jg.Files {
+			if slices.Contains(states, state) {
+				files = append(files, rel)
+			}
+		}
+		if len(files) == 0 {
+			continue
+		}
+		sort.Strings(files)
+		groups = append(groups, organise.DateGroup{
+			SourceDir: jg.SourceDir,
+			Files:     files,
+			Date:      jg.Date,
+			Camera:    jg.Camera,
+			Make:      jg.Make,
+			Model:     jg.Model,
+			Lens:      jg.Lens,
+		})
+	}
+	return groups
+}
+
+// paths returns the source paths of the files in one of states.
+func (j *Journal) paths(states ...string) []string {
+	var paths []string
+	for _, group := range j.dateGroups(states...) {
+		for _, rel := range group.Files {
+			paths = append(paths, filepath.Join(group.SourceDir, rel))
+		}
+	}
+	return paths
+}
+
+// mark moves the files at the given source paths to state.
+func (j *Journal) mark(paths []string, state string) {
+	for _, path := range paths {
+		for _, jg := range j.Groups {
+			rel, err := filepath.Rel(jg.SourceDir, path)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				continue
+			}
+			if _, ok := jg.Files[rel]; ok {
+				jg.Files[rel] = state
+				break
+			}
+		}
+	}
+}
+
+// dropMissing forgets pending files that are no longer on the card, so a resume
+// does not fail on files removed since the journal was written.
+func (j *Journal) dropMissing() {
+	for _, jg := range j.Groups {
+		for rel, state := range jg.Files {
+			if state != statePending {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(jg.SourceDir, rel)); os.IsNotExist(err) {
+				log.Warn().Str("file", filepath.Join(jg.SourceDir, rel)).Msg("file has gone from the card since it was journaled")
+				delete(jg.Files, rel)
+			}
+		}
+	}
+}
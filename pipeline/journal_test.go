@@ -0,0 +1,176 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DistroByte/photo-organiser/mount"
+	"github.com/DistroByte/photo-organiser/organise"
+	"github.com/DistroByte/photo-organiser/transfer"
+)
+
+// fakeTransferer records the dates of the groups it is given and fails those in
+// failDates, reporting a group's files as delivered only when it succeeds. When
+// set, after is called once each group is sent.
+type fakeTransferer struct {
+	name      string
+	failDates map[string]bool
+	after     func()
+	sent      []string
+}
+
+func (f *fakeTransferer) Name() string    { return f.name }
+func (f *fakeTransferer) Prepare() error  { return nil }
+func (f *fakeTransferer) Finalize() error { return nil }
+func (f *fakeTransferer) Verify(ctx context.Context, group organise.DateGroup) error {
+	return nil
+}
+
+func (f *fakeTransferer) Transfer(ctx context.Context, group organise.DateGroup) ([]string, error) {
+	f.sent = append(f.sent, group.Date)
+	if f.after != nil {
+		defer f.after()
+	}
+	if f.failDates[group.Date] {
+		return nil, errors.New("boom")
+	}
+	var done []string
+	for _, rel := range group.Files {
+		done = append(done, filepath.Join(group.SourceDir, rel))
+	}
+	return done, nil
+}
+
+func TestJournalResume(t *testing.T) {
+	p := &Pipeline{Card: mount.Card{Device: "/dev/sdz9"}, Cleanup: CleanupAlways, StateDir: t.TempDir()}
+
+	src := t.TempDir()
+	first := filepath.Join(src, "100MSDCF", "DSC00001.ARW")
+	second := filepath.Join(src, "101MSDCF", "DSC00002.ARW")
+	writeFile(t, first, "raw-1", time.Time{})
+	writeFile(t, second, "raw-2", time.Time{})
+	groups := []organise.DateGroup{
+		{SourceDir: filepath.Dir(first), Date: "2024-06-01"},
+		{SourceDir: filepath.Dir(second), Date: "2024-06-02"},
+	}
+	camera := organise.Sony
+
+	// The first run only gets one group across before failing.
+	flaky := &fakeTransferer{name: "flaky", failDates: map[string]bool{"2024-06-02": true}}
+	j, err := p.newJournal(camera.Name, src, []transfer.Transferer{flaky}, groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.run(t.Context(), camera, j, []transfer.Transferer{flaky}); !isStage(err, StageTransfer) {
+		t.Errorf("resume() = %v, want a transfer error", err)
+	}
+	if _, err := os.Stat(first); !os.IsNotExist(err) {
+		t.Error("the transferred file should have been cleaned up")
+	}
+
+	journals, err := p.IncompleteJournals()
+	if err != nil || len(journals) != 1 {
+		t.Fatalf("IncompleteJournals() = (%d journals, %v), want 1", len(journals), err)
+	}
+	j = journals[0]
+	if got := j.paths(statePending); !equalStrings(got, []string{second}) {
+		t.Errorf("pending = %v, want %v", got, []string{second})
+	}
+	if got := j.paths(stateCleaned); !equalStrings(got, []string{first}) {
+		t.Errorf("cleaned = %v, want %v", got, []string{first})
+	}
+
+	// Resuming sends only what is still pending.
+	good := &fakeTransferer{name: "good"}
+	if err := p.Resume(t.Context(), camera, j, []transfer.Transferer{good}); err != nil {
+		t.Errorf("resume() = %v", err)
+	}
+	if !equalStrings(good.sent, []string{"2024-06-02"}) {
+		t.Errorf("resume sent %v, want only the unfinished group", good.sent)
+	}
+	if _, err := os.Stat(second); !os.IsNotExist(err) {
+		t.Error("the resumed file should have been cleaned up")
+	}
+	if journals, _ := p.IncompleteJournals(); len(journals) != 0 {
+		t.Errorf("%d unfinished journal(s) left after resuming", len(journals))
+	}
+
+	// Both attempts are in the history.
+	records, err := p.History()
+	if err != nil || len(records) != 2 {
+		t.Fatalf("History() = (%d records, %v), want 2", len(records), err)
+	}
+	if r := records[0]; r.Resumed || r.Transferred != 1 || r.Cleaned != 1 || r.Failed != 1 || r.Bytes != 5 {
+		t.Errorf("first run record = %+v", r)
+	}
+	if r := records[1]; !r.Resumed || r.Files != 2 || r.Transferred != 1 || r.Failed != 0 {
+		t.Errorf("resumed run record = %+v", r)
+	}
+}
+
+func TestJournalInterruptedRun(t *testing.T) {
+	p := &Pipeline{Card: mount.Card{Device: "/dev/sdz9"}, Cleanup: CleanupAlways, StateDir: t.TempDir()}
+
+	src := t.TempDir()
+	first := filepath.Join(src, "100MSDCF", "DSC00001.ARW")
+	second := filepath.Join(src, "101MSDCF", "DSC00002.ARW")
+	writeFile(t, first, "raw-1", time.Time{})
+	writeFile(t, second, "raw-2", time.Time{})
+	groups := []organise.DateGroup{
+		{SourceDir: filepath.Dir(first), Date: "2024-06-01"},
+		{SourceDir: filepath.Dir(second), Date: "2024-06-02"},
+	}
+	camera := organise.Sony
+
+	// Interrupted once the first group is across.
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	tr := &fakeTransferer{name: "fake", after: cancel}
+	j, err := p.newJournal(camera.Name, src, []transfer.Transferer{tr}, groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.run(ctx, camera, j, []transfer.Transferer{tr}); !errors.Is(err, context.Canceled) {
+		t.Errorf("resume() = %v, want an interruption", err)
+	}
+	if _, err := os.Stat(first); err != nil {
+		t.Error("an interrupted run must not clean up")
+	}
+	if got := j.paths(stateTransferred); !equalStrings(got, []string{first}) {
+		t.Errorf("transferred = %v, want %v", got, []string{first})
+	}
+	if got := j.paths(statePending); !equalStrings(got, []string{second}) {
+		t.Errorf("pending = %v, want %v", got, []string{second})
+	}
+
+	records, err := p.History()
+	if err != nil || len(records) != 1 {
+		t.Fatalf("History() = (%d records, %v), want 1", len(records), err)
+	}
+	if r := records[0]; !r.Interrupted || r.Transferred != 1 || r.Failed != 1 || r.Status() != "interrupted" {
+		t.Errorf("interrupted run record = %+v", r)
+	}
+}
+
+func TestJournalDryRunIsNotSaved(t *testing.T) {
+	p := &Pipeline{Card: mount.Card{Device: "/dev/sdz9"}, DryRun: true, StateDir: t.TempDir()}
+
+	src := t.TempDir()
+	writeFile(t, filepath.Join(src, "DSC00001.ARW"), "raw", time.Time{})
+	if _, err := p.newJournal("sony", src, nil, []organise.DateGroup{{SourceDir: src, Date: "2024-06-01"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(p.journalDir(p.Card.ID())); !os.IsNotExist(err) {
+		t.Error("a dry run must not write a journal")
+	}
+}
+
+// isStage reports whether err is a stage error from stage.
+func isStage(err error, stage Stage) bool {
+	var pe *Error
+	return errors.As(err, &pe) && pe.Stage == stage
+}
@@ -0,0 +1,179 @@
+// Package pipeline offloads a camera's files from a mounted card: it groups them
+// by date, transfers the groups to every backend, optionally verifies them, and
+// cleans up the files every backend delivered. Each file's progress is journaled
+// so an interrupted run can be resumed, and every run is recorded in a history.
+package pipeline
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/DistroByte/photo-organiser/cache"
+	"github.com/DistroByte/photo-organiser/mount"
+	"github.com/DistroByte/photo-organiser/organise"
+	"github.com/DistroByte/photo-organiser/transfer"
+	"github.com/rs/zerolog/log"
+)
+
+// Pipeline holds the settings shared by every run on one card.
+type Pipeline struct {
+	Card              mount.Card   // the card; its ID keys the journals and Dir is the card root
+	DryRun            bool         // journal, record and clean up nothing; the transferers have their own DryRun
+	Verify            bool         // run a verification pass before cleanup
+	Cleanup           string       // cleanup policy, one of CleanupPolicies
+	CleanupUnattended string       // policy used instead of CleanupAsk when Unattended
+	Unattended        bool         // no one can answer a prompt
+	Trash             string       // directory cleaned files are moved into; empty deletes them
+	TrashRetention    int          // days to keep trash entries; 0 keeps them forever
+	StateDir          string       // journals and history; the upload cache's directory when empty
+	Prompt            io.Reader    // answers the cleanup prompt; os.Stdin when nil
+	Out               io.Writer    // receives the cleanup prompt; os.Stdout when nil
+	OnRun             func(Record) // called with each run's record once it is in the history
+}
+
+// stateDir resolves StateDir.
+func (p *Pipeline) stateDir() string {
+	return cmp.Or(p.StateDir, filepath.Dir(cache.DefaultPath()))
+}
+
+func (p *Pipeline) prompt() io.Reader {
+	if p.Prompt == nil {
+		return os.Stdin
+	}
+	return p.Prompt
+}
+
+func (p *Pipeline) out() io.Writer {
+	if p.Out == nil {
+		return os.Stdout
+	}
+	return p.Out
+}
+
+// WithCard mounts the card, read-only if asked, runs fn, and unmounts the card
+// again even when fn fails. fn's error wins; a failed unmount is only returned
+// when fn succeeded, and logged otherwise.
+func (p *Pipeline) WithCard(readOnly bool, fn func() error) (err error) {
+	if err := p.Card.Mount(readOnly); err != nil {
+		return StageError(StageMount, err)
+	}
+	defer func() {
+		if unmountErr := p.Card.Unmount(); unmountErr != nil {
+			if err == nil {
+				err = StageError(StageMount, unmountErr)
+			} else {
+				log.Error().Err(unmountErr).Msg("Failed to unmount drive")
+			}
+		}
+	}()
+	return fn()
+}
+
+// Process groups, transfers, and optionally cleans up one source directory of
+// camera's files on the already-mounted card.
+func (p *Pipeline) Process(ctx context.Context, camera organise.Camera, source string, transferers []transfer.Transferer) error {
+	groups, err := camera.Group(ctx, source)
+	if err != nil {
+		return StageError(StageGroup, fmt.Errorf("grouping %s files by date: %w", camera.Name, err))
+	}
+
+	organise.Annotate(ctx, groups, camera.Name)
+	j, err := p.newJournal(camera.Name, source, transferers, groups)
+	if err != nil {
+		return StageError(StageGroup, err)
+	}
+	return p.run(ctx, camera, j, transferers)
+}
+
+// Resume continues the unfinished run j of camera, sending only the files still
+// pending. Files that have gone from the card since are forgotten.
+func (p *Pipeline) Resume(ctx context.Context, camera organise.Camera, j *Journal, transferers []transfer.Transferer) error {
+	j.dropMissing()
+	j.resumed = true
+	return p.run(ctx, camera, j, transferers)
+}
+
+// run transfers the journal's pending files, optionally verifies, then cleans up,
+// recording each file's progress so an interrupted run can be continued.
+// Each call is recorded in the run history. A failed transfer does not stop the
+// delivered files being cleaned up, but is still returned. An interrupted run
+// skips verification and cleanup, leaving the rest for a resume.
+func (p *Pipeline) run(ctx context.Context, camera organise.Camera, j *Journal, transferers []transfer.Transferer) error {
+	start := time.Now()
+	rec := Record{
+		ID:       start.Format("20060102-150405") + "-" + camera.Name,
+		Started:  start,
+		Camera:   camera.Name,
+		Card:     j.Card,
+		Label:    p.Card.Label(),
+		Source:   j.Source,
+		Backends: j.Backends,
+		Resumed:  j.resumed,
+		Groups:   len(j.Groups),
+	}
+	for _, jg := range j.Groups {
+		rec.Files += len(jg.Files)
+	}
+	defer func() {
+		rec.Failed = len(j.paths(statePending))
+		rec.DurationSeconds = time.Since(start).Seconds()
+		p.recordRun(rec)
+		if p.OnRun != nil {
+			p.OnRun(rec)
+		}
+	}()
+
+	transferred, transferErr := transfer.Run(ctx, j.dateGroups(statePending), transferers)
+	if ctx.Err() != nil {
+		rec.Interrupted = true
+		rec.Transferred, rec.Bytes = len(transferred), totalSize(transferred)
+		j.mark(transferred, stateTransferred)
+		j.save()
+		log.Warn().
+			Str("camera", camera.Name).
+			Int("transferred", len(transferred)).
+			Int("pending", len(j.paths(statePending))).
+			Msg("run interrupted: skipping verification and cleanup, use resume to continue")
+		return StageError(StageTransfer, transferErr)
+	}
+	if transferErr != nil {
+		// Carry on: only the files every backend delivered are offered for cleanup.
+		log.Error().Err(transferErr).Str("camera", camera.Name).Msg("transfer failed")
+		transferErr = StageError(StageTransfer, transferErr)
+	}
+	rec.Transferred, rec.Bytes = len(transferred), totalSize(transferred)
+	j.mark(transferred, stateTransferred)
+	j.save()
+
+	policy := p.effectiveCleanupPolicy()
+	if (p.Verify || policy == CleanupVerified) && !p.DryRun {
+		if err := transfer.Verify(ctx, j.dateGroups(stateTransferred), transferers); err != nil {
+			if ctx.Err() != nil {
+				rec.Interrupted = true
+				log.Warn().Str("camera", camera.Name).Msg("verification interrupted: skipping cleanup")
+				return cmp.Or(transferErr, StageError(StageVerify, err))
+			}
+			log.Error().Err(err).Str("camera", camera.Name).Msg("refusing cleanup: verification failed")
+			rec.VerifyFailed = true
+			return cmp.Or(transferErr, StageError(StageVerify, err))
+		}
+		j.mark(j.paths(stateTransferred), stateVerified)
+		j.save()
+	}
+
+	removed, cleanupErr := p.promptAndCleanup(ctx, j.Source, j.paths(stateTransferred, stateVerified), camera.FlatCleanup, policy)
+	rec.Cleaned = len(removed)
+	j.mark(removed, stateCleaned)
+	j.Complete = len(j.paths(statePending)) == 0
+	j.save()
+	if len(removed) > 0 && camera.ClearSonyIndex {
+		cleanupSonyCardIndex(p.Card.Dir)
+	}
+	rec.Interrupted = ctx.Err() != nil
+	return cmp.Or(transferErr, StageError(StageCleanup, cleanupErr))
+}
@@ -0,0 +1,18 @@
+package pipeline
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithCardReturnsStageError(t *testing.T) {
+	p := &Pipeline{} // no mount type, so mounting is skipped and only fn can fail
+	ran := false
+	err := p.WithCard(false, func() error {
+		ran = true
+		return StageError(StageVerify, errors.New("missing"))
+	})
+	if !ran || !isStage(err, StageVerify) {
+		t.Errorf("WithCard() = %v (ran %v), want fn's verify error", err, ran)
+	}
+}
@@ -0,0 +1,73 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/DistroByte/photo-organiser/cache"
+	"github.com/DistroByte/photo-organiser/internal/workpool"
+	"github.com/DistroByte/photo-organiser/organise"
+	"github.com/DistroByte/photo-organiser/transfer/immich"
+	"github.com/rs/zerolog/log"
+)
+
+// PlanRow describes what a run would transfer of one date group.
+type PlanRow struct {
+	Camera string
+	Date   string
+	Source string
+	Files  int
+	Bytes  int64
+	Types  map[string]int // upper-case extension without the dot → file count
+	Cached int            // files the upload cache already knows
+}
+
+// Plan summarises camera's groups without changing anything, hashing each file
+// with workers goroutines to look it up in uploaded. It returns ctx's error once
+// ctx is done, without hashing any further file.
+func Plan(ctx context.Context, camera string, groups []organise.DateGroup, uploaded *cache.Cache, workers int) ([]PlanRow, error) {
+	var rows []PlanRow
+	for _, group := range groups {
+		files, err := group.ListFiles()
+		if err != nil {
+			return nil, err
+		}
+		row := PlanRow{Camera: camera, Date: group.Date, Source: group.SourceDir, Files: len(files), Types: make(map[string]int)}
+		var mu sync.Mutex
+		workpool.Each(workers, files, func(rel string) {
+			if ctx.Err() != nil {
+				return
+			}
+			path := filepath.Join(group.SourceDir, rel)
+			info, err := os.Stat(path)
+			if err != nil {
+				log.Warn().Err(err).Str("file", path).Msg("cannot read file")
+				return
+			}
+			cached := false
+			if checksum, err := immich.Checksum(path); err == nil {
+				_, cached = uploaded.Has(checksum)
+			}
+			ext := strings.ToUpper(strings.TrimPrefix(filepath.Ext(rel), "."))
+			if ext == "" {
+				ext = "-"
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			row.Bytes += info.Size()
+			row.Types[ext]++
+			if cached {
+				row.Cached++
+			}
+		})
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
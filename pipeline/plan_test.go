@@ -0,0 +1,55 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DistroByte/photo-organiser/cache"
+	"github.com/DistroByte/photo-organiser/organise"
+	"github.com/DistroByte/photo-organiser/transfer/immich"
+)
+
+func TestPlan(t *testing.T) {
+	src := t.TempDir()
+	writeFile(t, filepath.Join(src, "DSC00001.ARW"), "raw-1", time.Time{})
+	writeFile(t, filepath.Join(src, "DSC00001.JPG"), "jpeg-1", time.Time{})
+	writeFile(t, filepath.Join(src, "DSC00002.ARW"), "raw-2", time.Time{})
+
+	// One file cached by checksum, another only by a legacy name:size key, which a
+	// run would not trust either.
+	checksum, _ := immich.Checksum(filepath.Join(src, "DSC00001.JPG"))
+	data, _ := json.Marshal(map[string]any{
+		"version": 2,
+		"assets":  map[string]string{checksum: "asset-1"},
+		"legacy":  map[string]string{"DSC00002.ARW:5": "asset-2"},
+	})
+	cachePath := filepath.Join(t.TempDir(), "uploaded.json")
+	if err := os.WriteFile(cachePath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	uploaded := cache.Load(cachePath)
+	groups := []organise.DateGroup{{SourceDir: src, Date: "2024-06-01"}}
+
+	rows, err := Plan(t.Context(), "sony", groups, uploaded, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+	row := rows[0]
+	if row.Files != 3 || row.Bytes != 16 || row.Cached != 1 || row.Types["ARW"] != 2 || row.Types["JPG"] != 1 {
+		t.Errorf("row = %+v", row)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := Plan(ctx, "sony", groups, uploaded, 2); !errors.Is(err, context.Canceled) {
+		t.Errorf("Plan() after cancellation = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,75 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/DistroByte/photo-organiser/transfer/local"
+	"github.com/rs/zerolog/log"
+)
+
+// trashEntryLayout names the trash entry of each cleanup by when it ran.
+const trashEntryLayout = "20060102-150405"
+
+// moveToTrash moves path into the trash entry directory, keeping its location
+// relative to cardRoot (or to sourceDir when it is not on the card) so it can be
+// put back. Moving off the card falls back to a verified copy and delete.
+func moveToTrash(entry, cardRoot, sourceDir, path string) error {
+	rel, err := filepath.Rel(cardRoot, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		if rel, err = filepath.Rel(sourceDir, path); err != nil {
+			return err
+		}
+	}
+	dst := filepath.Join(entry, rel)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	err = os.Rename(path, dst)
+	if errors.Is(err, syscall.EXDEV) {
+		if err = local.CopyFile(path, dst); err == nil {
+			err = os.Remove(path)
+		}
+	}
+	return err
+}
+
+// PurgeTrash removes the entries in Trash that are older than TrashRetention
+// days, returning how many it removed. A retention of zero or less keeps
+// everything, and a dry run only logs what it would remove.
+func (p *Pipeline) PurgeTrash(now time.Time) (int, error) {
+	if p.TrashRetention <= 0 || p.Trash == "" {
+		return 0, nil
+	}
+	entries, err := os.ReadDir(p.Trash)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	cutoff := now.AddDate(0, 0, -p.TrashRetention)
+	var purged int
+	for _, e := range entries {
+		created, err := time.ParseInLocation(trashEntryLayout, e.Name(), time.Local)
+		if !e.IsDir() || err != nil || !created.Before(cutoff) {
+			continue
+		}
+		path := filepath.Join(p.Trash, e.Name())
+		if p.DryRun {
+			log.Info().Str("entry", path).Msg("[dry-run] would purge trash entry")
+			continue
+		}
+		log.Debug().Str("entry", path).Msg("purging trash entry")
+		if err := os.RemoveAll(path); err != nil {
+			return purged, fmt.Errorf("purging %s: %w", path, err)
+		}
+		purged++
+	}
+	return purged, nil
+}
@@ -0,0 +1,64 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DistroByte/photo-organiser/mount"
+	"github.com/DistroByte/photo-organiser/organise"
+)
+
+func TestCleanupFilesMovesToTrash(t *testing.T) {
+	p := &Pipeline{Card: mount.Card{Dir: t.TempDir()}}
+	src := filepath.Join(p.Card.Dir, "DCIM")
+	path := filepath.Join(src, "100MSDCF", "DSC00001.ARW")
+	writeFile(t, path, "raw", time.Time{})
+
+	entry := filepath.Join(p.Card.Dir, organise.TrashDirName, "20240601-120000")
+	if removed := p.cleanupFiles(src, []string{path}, entry); len(removed) != 1 {
+		t.Fatalf("trashed %v, want one file", removed)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("trashed file is still in the source")
+	}
+	data, err := os.ReadFile(filepath.Join(entry, "DCIM", "100MSDCF", "DSC00001.ARW"))
+	if err != nil || string(data) != "raw" {
+		t.Errorf("trashed file = (%q, %v), want it under its card path", data, err)
+	}
+
+	// The trash on the card must not be reported as left over or regrouped.
+	left, err := leftoverFiles(p.Card.Dir, false)
+	if err != nil || len(left) != 0 {
+		t.Errorf("leftoverFiles() = (%v, %v), want nothing", left, err)
+	}
+}
+
+func TestPurgeTrash(t *testing.T) {
+	root := t.TempDir()
+	now := time.Date(2024, time.June, 30, 12, 0, 0, 0, time.Local)
+	for _, name := range []string{"20240501-090000", "20240625-090000", "notes"} {
+		writeFile(t, filepath.Join(root, name, "DSC00001.ARW"), "raw", time.Time{})
+	}
+
+	purged, err := (&Pipeline{Trash: root, TrashRetention: 30}).PurgeTrash(now)
+	if err != nil || purged != 1 {
+		t.Fatalf("PurgeTrash() = (%d, %v), want 1 entry", purged, err)
+	}
+	entries, _ := os.ReadDir(root)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if !equalStrings(names, []string{"20240625-090000", "notes"}) {
+		t.Errorf("remaining entries = %v", names)
+	}
+
+	if purged, _ := (&Pipeline{Trash: root}).PurgeTrash(now.AddDate(1, 0, 0)); purged != 0 {
+		t.Errorf("retention 0 purged %d entries, want none", purged)
+	}
+	if purged, err := (&Pipeline{Trash: filepath.Join(root, "missing"), TrashRetention: 30}).PurgeTrash(now); purged != 0 || err != nil {
+		t.Errorf("missing trash = (%d, %v), want nothing to do", purged, err)
+	}
+}
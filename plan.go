@@ -0,0 +1,114 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/DistroByte/photo-organiser/cache"
+	"github.com/DistroByte/photo-organiser/organise"
+	"github.com/DistroByte/photo-organiser/pipeline"
+	"github.com/DistroByte/photo-organiser/progress"
+	"github.com/DistroByte/photo-organiser/transfer"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// printPlan writes rows as a table followed by a total.
+func printPlan(w io.Writer, rows []pipeline.PlanRow) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "CAMERA\tDATE\tSOURCE\tFILES\tSIZE\tTYPES\tCACHED")
+	var files, cached int
+	var bytes int64
+	for _, row := range rows {
+		exts := make([]string, 0, len(row.Types))
+		for ext := range row.Types {
+			exts = append(exts, ext)
+		}
+		sort.Strings(exts)
+		types := make([]string, len(exts))
+		for i, ext := range exts {
+			types[i] = fmt.Sprintf("%s:%d", ext, row.Types[ext])
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%d/%d\n",
+			row.Camera, row.Date, row.Source, row.Files, progress.HumanBytes(row.Bytes), strings.Join(types, " "), row.Cached, row.Files)
+		files += row.Files
+		bytes += row.Bytes
+		cached += row.Cached
+	}
+	_, _ = fmt.Fprintf(tw, "TOTAL\t%d group(s)\t\t%d\t%s\t\t%d/%d\n", len(rows), files, progress.HumanBytes(bytes), cached, files)
+	_ = tw.Flush()
+}
+
+// runPlan mounts the card read-only and prints what a run of each named camera,
+// or of every detected camera, would transfer. A camera that cannot be grouped
+// is reported and left out of the table.
+func runPlan(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	for _, name := range args {
+		if _, ok := camerasByName[name]; !ok {
+			return fmt.Errorf("unknown camera %q", name)
+		}
+	}
+
+	var rows []pipeline.PlanRow
+	p := newPipeline()
+	err := p.WithCard(true, func() error {
+		names := args
+		if len(names) == 0 {
+			names = organise.Detect(directory)
+			if len(names) == 0 {
+				log.Warn().Str("mount_point", directory).Msg("no known camera layout found on card")
+			}
+		}
+
+		uploaded := cache.Load(cache.DefaultPath())
+		var firstErr error
+		for _, name := range names {
+			camera := camerasByName[name]
+			source := sourceDir
+			if source == "" {
+				source = camera.SourceDir(directory)
+			}
+			groups, err := camera.Group(ctx, source)
+			if err == nil {
+				var planned []pipeline.PlanRow
+				if planned, err = pipeline.Plan(ctx, name, groups, uploaded, concurrency); err == nil {
+					rows = append(rows, planned...)
+					continue
+				}
+			}
+			if ctx.Err() != nil {
+				return err
+			}
+			log.Error().Err(err).Str("camera", name).Msg("failed to group files by date")
+			firstErr = cmp.Or(firstErr, pipeline.StageError(pipeline.StageGroup, err))
+		}
+		return firstErr
+	})
+	if err == nil || len(rows) > 0 {
+		reportPlan(rows)
+	}
+	return err
+}
+
+// reportPlan prints rows as a table, or emits one plan_group event per row with
+// --output=json.
+func reportPlan(rows []pipeline.PlanRow) {
+	if outputFormat != outputJSON {
+		printPlan(os.Stdout, rows)
+		return
+	}
+	for _, row := range rows {
+		emit(event{
+			Event:  transfer.Event{Name: eventPlanGroup, Camera: row.Camera, Date: row.Date, Source: row.Source, Files: row.Files},
+			Bytes:  row.Bytes,
+			Types:  row.Types,
+			Cached: row.Cached,
+		})
+	}
+}
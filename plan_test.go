@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/DistroByte/photo-organiser/pipeline"
+)
+
+func TestReportPlan(t *testing.T) {
+	rows := []pipeline.PlanRow{{
+		Camera: "sony",
+		Date:   "2024-06-01",
+		Source: "/media/sd/DCIM",
+		Files:  3,
+		Bytes:  16,
+		Types:  map[string]int{"ARW": 2, "JPG": 1},
+		Cached: 1,
+	}}
+
+	events := captureEvents(t)
+	reportPlan(rows)
+	if got := events(); len(got) != 1 || got[0].Name != eventPlanGroup || got[0].Files != 3 || got[0].Bytes != 16 || got[0].Cached != 1 || got[0].Types["ARW"] != 2 {
+		t.Errorf("plan events = %+v", got)
+	}
+
+	var out bytes.Buffer
+	printPlan(&out, rows)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("plan output has %d lines, want header, row and total:\n%s", len(lines), out.String())
+	}
+	if fields := strings.Fields(lines[1]); !slices.Equal(fields[3:], []string{"3", "16", "B", "ARW:2", "JPG:1", "1/3"}) {
+		t.Errorf("row line = %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "TOTAL") || !strings.HasSuffix(lines[2], "1/3") {
+		t.Errorf("total line = %q", lines[2])
+	}
+}
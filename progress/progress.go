@@ -0,0 +1,224 @@
+// Package progress reports long-running uploads: as a line redrawn at the bottom
+// of the terminal, or as periodic log lines when stderr is not a terminal.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	progressRedraw      = 250 * time.Millisecond // progress line refresh on a terminal
+	progressLogInterval = 10 * time.Second       // progress log line otherwise
+)
+
+// IsTerminal reports whether f is a terminal rather than a pipe, a file, or
+// another character device such as /dev/null.
+func IsTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd())
+}
+
+// StderrIsTerminal reports whether progress can be drawn on stderr.
+var StderrIsTerminal = func() bool { return IsTerminal(os.Stderr) }
+
+// StatusWriter serialises writes to stderr so a progress line can stay at the
+// bottom of the terminal: other output clears the line before it is written, and
+// the next progress update draws it again.
+type StatusWriter struct {
+	mu   sync.Mutex
+	w    io.Writer
+	line bool // a progress line is on screen
+}
+
+// Stderr is the StatusWriter for os.Stderr. Everything written to stderr while
+// an upload runs, logs included, should go through it.
+var Stderr = NewStatusWriter(os.Stderr)
+
+// NewStatusWriter returns a StatusWriter that writes to w.
+func NewStatusWriter(w io.Writer) *StatusWriter {
+	return &StatusWriter{w: w}
+}
+
+func (s *StatusWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.line {
+		_, _ = io.WriteString(s.w, "\r\033[K")
+		s.line = false
+	}
+	return s.w.Write(p)
+}
+
+// Status replaces the progress line with line.
+func (s *StatusWriter) Status(line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _ = fmt.Fprintf(s.w, "\r\033[K%s", line)
+	s.line = true
+}
+
+// EndStatus leaves the progress line on screen and moves below it.
+func (s *StatusWriter) EndStatus() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.line {
+		_, _ = io.WriteString(s.w, "\n")
+		s.line = false
+	}
+}
+
+// Upload aggregates files and bytes across upload workers and reports them: as a
+// redrawn line when stderr is a terminal, else as periodic log lines. A nil
+// *Upload reports nothing.
+type Upload struct {
+	files, totalFiles atomic.Int64
+	bytes, totalBytes atomic.Int64
+	started           time.Time
+	stop, done        chan struct{}
+}
+
+// StartUpload starts reporting an upload of files files totalling bytes bytes.
+// Call Finish when it is done.
+func StartUpload(files int, bytes int64) *Upload {
+	p := &Upload{started: time.Now(), stop: make(chan struct{}), done: make(chan struct{})}
+	p.totalFiles.Store(int64(files))
+	p.totalBytes.Store(bytes)
+
+	tty := StderrIsTerminal()
+	interval := progressLogInterval
+	if tty {
+		interval = progressRedraw
+	}
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-p.stop:
+				if tty {
+					Stderr.Status(p.String())
+					Stderr.EndStatus()
+				} else {
+					p.log("upload finished")
+				}
+				return
+			}
+			if tty {
+				Stderr.Status(p.String())
+			} else {
+				p.log("upload progress")
+			}
+		}
+	}()
+	return p
+}
+
+// Finish stops reporting after a final update.
+func (p *Upload) Finish() {
+	if p == nil {
+		return
+	}
+	close(p.stop)
+	<-p.done
+}
+
+// Reader counts bytes read from r towards the progress, and into sent so a failed
+// attempt can be taken back out.
+func (p *Upload) Reader(r io.Reader, sent *atomic.Int64) io.Reader {
+	if p == nil {
+		return r
+	}
+	return &countingReader{r: r, p: p, sent: sent}
+}
+
+// Retract takes back bytes counted for an attempt that will be sent again, or
+// that failed for good.
+func (p *Upload) Retract(n int64) {
+	if p != nil {
+		p.bytes.Add(-n)
+	}
+}
+
+// FileDone counts a file as uploaded.
+func (p *Upload) FileDone() {
+	if p != nil {
+		p.files.Add(1)
+	}
+}
+
+// FileFailed drops a file of size bytes that will not be uploaded from the totals.
+func (p *Upload) FileFailed(size int64) {
+	if p != nil {
+		p.totalFiles.Add(-1)
+		p.totalBytes.Add(-size)
+	}
+}
+
+// rate returns the throughput so far in bytes per second and the estimated time
+// left, which is zero until something has been sent.
+func (p *Upload) rate() (float64, time.Duration) {
+	elapsed := time.Since(p.started).Seconds()
+	sent := p.bytes.Load()
+	if elapsed <= 0 || sent <= 0 {
+		return 0, 0
+	}
+	perSecond := float64(sent) / elapsed
+	left := float64(p.totalBytes.Load()-sent) / perSecond
+	return perSecond, time.Duration(max(left, 0) * float64(time.Second)).Round(time.Second)
+}
+
+func (p *Upload) String() string {
+	perSecond, eta := p.rate()
+	return fmt.Sprintf("Uploading %d/%d files  %s / %s  %s/s  ETA %s",
+		p.files.Load(), p.totalFiles.Load(),
+		HumanBytes(p.bytes.Load()), HumanBytes(p.totalBytes.Load()),
+		HumanBytes(int64(perSecond)), eta)
+}
+
+func (p *Upload) log(msg string) {
+	perSecond, eta := p.rate()
+	log.Info().
+		Int64("files", p.files.Load()).
+		Int64("total_files", p.totalFiles.Load()).
+		Str("sent", HumanBytes(p.bytes.Load())).
+		Str("total", HumanBytes(p.totalBytes.Load())).
+		Str("rate", HumanBytes(int64(perSecond))+"/s").
+		Str("eta", eta.String()).
+		Msg(msg)
+}
+
+type countingReader struct {
+	r    io.Reader
+	p    *Upload
+	sent *atomic.Int64
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.sent.Add(int64(n))
+	c.p.bytes.Add(int64(n))
+	return n, err
+}
+
+// HumanBytes formats n with a binary unit, e.g. "1.5 GiB".
+func HumanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,78 @@
+package progress
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestUploadCountsBytes(t *testing.T) {
+	orig := StderrIsTerminal
+	StderrIsTerminal = func() bool { return false }
+	t.Cleanup(func() { StderrIsTerminal = orig })
+
+	p := StartUpload(3, 20)
+	var sent atomic.Int64
+	if _, err := io.Copy(io.Discard, p.Reader(strings.NewReader("0123456789"), &sent)); err != nil {
+		t.Fatal(err)
+	}
+	p.FileDone()
+
+	// A retried attempt is taken back out before the file is streamed again.
+	var retried atomic.Int64
+	_, _ = io.Copy(io.Discard, p.Reader(strings.NewReader("abc"), &retried))
+	p.Retract(retried.Swap(0))
+	_, _ = io.Copy(io.Discard, p.Reader(strings.NewReader("abcdef"), &retried))
+	p.FileDone()
+
+	p.FileFailed(4)
+	p.Finish()
+
+	if got := p.bytes.Load(); got != 16 {
+		t.Errorf("bytes = %d, want 16", got)
+	}
+	if p.files.Load() != 2 || p.totalFiles.Load() != 2 || p.totalBytes.Load() != 16 {
+		t.Errorf("files %d/%d, total bytes %d; want 2/2 and 16", p.files.Load(), p.totalFiles.Load(), p.totalBytes.Load())
+	}
+	if s := p.String(); !strings.HasPrefix(s, "Uploading 2/2 files  16 B / 16 B") || !strings.HasSuffix(s, "ETA 0s") {
+		t.Errorf("String() = %q", s)
+	}
+
+	var nilProgress *Upload
+	r := strings.NewReader("x")
+	if nilProgress.Reader(r, &sent) != io.Reader(r) {
+		t.Error("a nil progress must not wrap the reader")
+	}
+	nilProgress.FileDone()
+	nilProgress.Finish()
+}
+
+func TestStatusWriterClearsProgressLine(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewStatusWriter(&buf)
+	s.Status("Uploading 1/2 files")
+	_, _ = s.Write([]byte("log line\n"))
+	s.Status("Uploading 2/2 files")
+	s.EndStatus()
+
+	want := "\r\033[KUploading 1/2 files\r\033[Klog line\n\r\033[KUploading 2/2 files\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestHumanBytes(t *testing.T) {
+	for n, want := range map[int64]string{
+		512:             "512 B",
+		1536:            "1.5 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+		3 << 30:         "3.0 GiB",
+		1<<40 + 1<<39:   "1.5 TiB",
+	} {
+		if got := HumanBytes(n); got != want {
+			t.Errorf("HumanBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
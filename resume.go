@@ -0,0 +1,66 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// runResume continues every unfinished run recorded for the card in --device,
+// using the backends that run used unless --backend is given. A failing run does
+// not stop the others, but an interruption does; the first failure is returned.
+func runResume(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	p := newPipeline()
+	journals, err := p.IncompleteJournals()
+	if err != nil {
+		return fmt.Errorf("reading run journals: %w", err)
+	}
+	if len(journals) == 0 {
+		log.Info().Str("card", p.Card.ID()).Msg("no unfinished run to resume for this card")
+		return nil
+	}
+
+	var uploaded bool
+	err = p.WithCard(false, func() error {
+		var firstErr error
+		for _, j := range journals {
+			camera, ok := camerasByName[j.Camera]
+			if !ok {
+				log.Warn().Str("camera", j.Camera).Msg("skipping run for an unknown camera")
+				continue
+			}
+			names := backends
+			if len(names) == 0 {
+				names = j.Backends
+			}
+			transferers, err := selectBackends(camera, names)
+			if err != nil {
+				log.Warn().Err(err).Str("camera", j.Camera).Msg("skipping run")
+				continue
+			}
+			log.Info().Str("camera", j.Camera).Time("started", j.Started).Msg("resuming run")
+			if err := p.Resume(ctx, camera, j, transferers); err != nil {
+				log.Error().Err(err).Str("camera", j.Camera).Msg("resumed run failed")
+				if ctx.Err() != nil {
+					return err
+				}
+				firstErr = cmp.Or(firstErr, err)
+			}
+			if usesBackend(transferers, backendImmich) {
+				uploaded = true
+			}
+		}
+		return firstErr
+	})
+	if err != nil {
+		return err
+	}
+
+	if uploaded && immichLibrary != "" {
+		return triggerSync(ctx)
+	}
+	return nil
+}
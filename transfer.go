@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/DistroByte/photo-organiser/organise"
+	"github.com/DistroByte/photo-organiser/transfer"
+	"github.com/DistroByte/photo-organiser/transfer/immich"
+	"github.com/DistroByte/photo-organiser/transfer/local"
+	"github.com/DistroByte/photo-organiser/transfer/rsync"
+)
+
+const (
+	backendRsync  = rsync.Name
+	backendImmich = immich.Name
+	backendLocal  = local.Name
+)
+
+var backendNames = []string{backendRsync, backendImmich, backendLocal}
+
+// newTransferer builds the backend called name from the command-line flags.
+func newTransferer(name string) (transfer.Transferer, error) {
+	switch name {
+	case backendRsync:
+		if !rsyncConfigured() {
+			return nil, fmt.Errorf("provide --host and --remote-path for rsync")
+		}
+		return &rsync.Transferer{
+			User:         remoteUser,
+			Host:         remoteHost,
+			Path:         remotePath,
+			DestTemplate: destTemplate,
+			DryRun:       dryRun,
+			Stdout:       textOut(),
+		}, nil
+	case backendImmich:
+		if immichServer == "" || immichKey == "" {
+			return nil, fmt.Errorf("provide --server and --key for the Immich API")
+		}
+		return &immich.Transferer{
+			Client:      immichClient(),
+			Album:       albumTemplate,
+			Concurrency: concurrency,
+			DryRun:      dryRun,
+		}, nil
+	case backendLocal:
+		if localPath == "" {
+			return nil, fmt.Errorf("provide --local-path for a local copy")
+		}
+		return &local.Transferer{Path: localPath, DestTemplate: destTemplate, DryRun: dryRun}, nil
+	}
+	return nil, fmt.Errorf("unknown backend %q (want one of %v)", name, backendNames)
+}
+
+// immichClient returns a client for --server and --key.
+func immichClient() *immich.Client {
+	return &immich.Client{
+		Server:     immichServer,
+		Key:        immichKey,
+		HTTP:       httpClient,
+		Retries:    retries,
+		RetryDelay: retryDelay,
+	}
+}
+
+// selectBackends returns the named transferers for camera, usually --backend.
+// Without names the first configured destination is used: Immich when --server and
+// --key are set, then rsync when --host and --remote-path are set, then --local-path.
+func selectBackends(camera organise.Camera, names []string) ([]transfer.Transferer, error) {
+	if len(names) == 0 {
+		switch {
+		case !camera.NoImmich && immichServer != "" && immichKey != "":
+			names = []string{backendImmich}
+		case rsyncConfigured():
+			names = []string{backendRsync}
+		case localPath != "":
+			names = []string{backendLocal}
+		default:
+			if camera.NoImmich {
+				return nil, fmt.Errorf("provide --host and --remote-path for rsync, or --local-path")
+			}
+			return nil, fmt.Errorf("provide --server + --key (Immich API), --host + --remote-path (rsync), or --local-path (local copy)")
+		}
+	}
+
+	var transferers []transfer.Transferer
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if name == backendImmich && camera.NoImmich {
+			return nil, fmt.Errorf("%s cannot use the %s backend", camera.Name, backendImmich)
+		}
+		t, err := newTransferer(name)
+		if err != nil {
+			return nil, err
+		}
+		transferers = append(transferers, t)
+	}
+	return transferers, nil
+}
+
+func usesBackend(transferers []transfer.Transferer, name string) bool {
+	return slices.ContainsFunc(transferers, func(t transfer.Transferer) bool { return t.Name() == name })
+}
@@ -0,0 +1,49 @@
+package transfer
+
+// Event names, in the order a backend reports them.
+const (
+	EventGroupStarted   = "group_started"
+	EventFileUploaded   = "file_uploaded"
+	EventFileSkipped    = "file_skipped"
+	EventFileFailed     = "file_failed"
+	EventGroupDone      = "group_done"
+	EventBackendSummary = "backend_summary"
+	EventVerification   = "verification"
+)
+
+// Event reports progress during a run, for scripts and dashboards. Fields that do
+// not apply to an event are left out.
+type Event struct {
+	Name        string  `json:"event"`
+	Camera      string  `json:"camera,omitempty"`
+	Backend     string  `json:"backend,omitempty"`
+	Date        string  `json:"date,omitempty"`
+	Source      string  `json:"source,omitempty"`
+	File        string  `json:"file,omitempty"`
+	AssetID     string  `json:"asset_id,omitempty"`
+	Reason      string  `json:"reason,omitempty"`
+	Groups      int     `json:"groups,omitempty"`
+	Files       int     `json:"files,omitempty"`
+	Transferred int     `json:"transferred,omitempty"`
+	Failed      int     `json:"failed,omitempty"`
+	Error       string  `json:"error,omitempty"`
+	Elapsed     float64 `json:"elapsed_seconds,omitempty"`
+}
+
+// Notify receives every event, when set. Upload workers call it concurrently.
+var Notify func(Event)
+
+// Emit passes ev to Notify.
+func Emit(ev Event) {
+	if Notify != nil {
+		Notify(ev)
+	}
+}
+
+// ErrString returns err's message, or "" for a nil error.
+func ErrString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,136 @@
+package immich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/DistroByte/photo-organiser/organise"
+	"github.com/rs/zerolog/log"
+)
+
+// albumBatch bounds how many asset IDs are added to an album per request.
+const albumBatch = 1000
+
+// errAlbumGone is returned when a cached album no longer exists on the server.
+var errAlbumGone = errors.New("album not found")
+
+type album struct {
+	ID        string `json:"id"`
+	AlbumName string `json:"albumName"`
+}
+
+type bulkIDResult struct {
+	ID      string `json:"id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error"` // e.g. "duplicate" when already in the album
+}
+
+// addToAlbum adds assetIDs to the album named by rendering t.Album for group,
+// reusing an album recorded in the cache or found on the server and creating one
+// otherwise. A cached album deleted on the server is recreated once.
+func (t *Transferer) addToAlbum(ctx context.Context, group organise.DateGroup, assetIDs []string) error {
+	name, err := organise.RenderTemplate("album", t.Album, group)
+	if err != nil {
+		return err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("album template %q rendered an empty name", t.Album)
+	}
+
+	for attempt := 0; ; attempt++ {
+		albumID, err := t.ensureAlbum(ctx, name)
+		if err != nil {
+			return err
+		}
+		err = t.Client.addAlbumAssets(ctx, albumID, assetIDs)
+		if errors.Is(err, errAlbumGone) && attempt == 0 {
+			log.Warn().Str("album", name).Msg("cached album no longer exists, recreating")
+			t.cache.MarkAlbum(name, "")
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		log.Info().Str("album", name).Int("assets", len(assetIDs)).Msg("added to album")
+		return nil
+	}
+}
+
+// ensureAlbum returns the ID of the album called name, looking in the cache, then
+// on the server, and finally creating it.
+func (t *Transferer) ensureAlbum(ctx context.Context, name string) (string, error) {
+	if id, ok := t.cache.Album(name); ok {
+		return id, nil
+	}
+	if id, ok, err := t.findAlbum(ctx, name); err != nil || ok {
+		return id, err
+	}
+
+	// Creating an album is not idempotent: retrying a request the server had
+	// already carried out before failing would make a duplicate. So it is sent
+	// once, and on failure the albums are listed again in case it went through.
+	var created album
+	once := *t.Client
+	once.Retries = 0
+	if err := once.requestJSON(ctx, http.MethodPost, "/albums", map[string]string{"albumName": name}, http.StatusCreated, &created); err != nil {
+		if id, ok, findErr := t.findAlbum(ctx, name); findErr == nil && ok {
+			log.Warn().Err(err).Str("album", name).Msg("creating album reported an error, but it exists")
+			return id, nil
+		}
+		return "", fmt.Errorf("creating album: %w", err)
+	}
+	log.Info().Str("album", name).Str("id", created.ID).Msg("created album")
+	t.cache.MarkAlbum(name, created.ID)
+	return created.ID, nil
+}
+
+// findAlbum looks for an album called name on the server, caching its ID if found.
+func (t *Transferer) findAlbum(ctx context.Context, name string) (string, bool, error) {
+	var albums []album
+	if err := t.Client.requestJSON(ctx, http.MethodGet, "/albums", nil, http.StatusOK, &albums); err != nil {
+		return "", false, fmt.Errorf("listing albums: %w", err)
+	}
+	for _, a := range albums {
+		if a.AlbumName == name {
+			t.cache.MarkAlbum(name, a.ID)
+			return a.ID, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+func (c *Client) addAlbumAssets(ctx context.Context, albumID string, assetIDs []string) error {
+	for start := 0; start < len(assetIDs); start += albumBatch {
+		batch := assetIDs[start:min(start+albumBatch, len(assetIDs))]
+		var results []bulkIDResult
+		err := c.requestJSON(ctx, http.MethodPut, "/albums/"+albumID+"/assets", map[string][]string{"ids": batch}, http.StatusOK, &results)
+		if albumGone(err) {
+			return fmt.Errorf("%w: %s", errAlbumGone, albumID)
+		}
+		if err != nil {
+			return fmt.Errorf("adding assets to album: %w", err)
+		}
+		for _, r := range results {
+			if !r.Success && r.Error != "duplicate" {
+				log.Warn().Str("id", r.ID).Str("error", r.Error).Msg("asset not added to album")
+			}
+		}
+	}
+	return nil
+}
+
+// albumGone reports whether err says the album does not exist: a 404, or the 400
+// Immich answers with for an album that is missing or not the caller's. Any other
+// 400, such as a malformed asset ID, leaves the cached album alone.
+func albumGone(err error) bool {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusNotFound ||
+		(apiErr.StatusCode == http.StatusBadRequest && strings.Contains(strings.ToLower(apiErr.Message), "not found"))
+}
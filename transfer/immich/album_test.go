@@ -0,0 +1,147 @@
+package immich
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DistroByte/photo-organiser/cache"
+	"github.com/DistroByte/photo-organiser/organise"
+)
+
+// fakeAlbums serves the Immich album endpoints used by addToAlbum.
+type fakeAlbums struct {
+	albums     map[string]string   // ID → name
+	members    map[string][]string // album ID → asset IDs
+	created    int
+	failCreate bool // create the album, then report a server error
+}
+
+func (f *fakeAlbums) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/albums":
+		var out []album
+		for id, name := range f.albums {
+			out = append(out, album{ID: id, AlbumName: name})
+		}
+		_ = json.NewEncoder(w).Encode(out)
+	case r.Method == http.MethodPost && r.URL.Path == "/albums":
+		var in album
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		f.created++
+		id := "album-" + in.AlbumName
+		f.albums[id] = in.AlbumName
+		if f.failCreate {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(album{ID: id, AlbumName: in.AlbumName})
+	case r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/assets"):
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/albums/"), "/assets")
+		if _, ok := f.albums[id]; !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"message":"Not found or no album.update access","statusCode":400}`))
+			return
+		}
+		var in struct {
+			IDs []string `json:"ids"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		var out []bulkIDResult
+		for _, assetID := range in.IDs {
+			if assetID == "not-a-uuid" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"message":"each value in ids must be a UUID","statusCode":400}`))
+				return
+			}
+			f.members[id] = append(f.members[id], assetID)
+			out = append(out, bulkIDResult{ID: assetID, Success: true})
+		}
+		_ = json.NewEncoder(w).Encode(out)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestAddToAlbum(t *testing.T) {
+	fake := &fakeAlbums{
+		albums:  map[string]string{"existing-id": "2024-06-01 sony"},
+		members: make(map[string][]string),
+	}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	tr := &Transferer{
+		Client: &Client{Server: srv.URL, Key: "test-key"},
+		Album:  "{{.Date}} {{.Camera}}",
+		cache:  cache.Load(filepath.Join(t.TempDir(), "uploaded.json")),
+	}
+
+	// An album that already exists on the server is reused and cached.
+	group := organise.DateGroup{Date: "2024-06-01", Camera: "sony"}
+	if err := tr.addToAlbum(t.Context(), group, []string{"a1", "a2"}); err != nil {
+		t.Fatal(err)
+	}
+	if fake.created != 0 || len(fake.members["existing-id"]) != 2 {
+		t.Errorf("created=%d members=%v, want the existing album reused", fake.created, fake.members)
+	}
+	if id, ok := tr.cache.Album("2024-06-01 sony"); !ok || id != "existing-id" {
+		t.Errorf("cached album = (%q, %v), want existing-id", id, ok)
+	}
+
+	// A new name creates the album once; reruns use the cached ID.
+	group = organise.DateGroup{Date: "2024-06-02", Camera: "sony"}
+	for range 2 {
+		if err := tr.addToAlbum(t.Context(), group, []string{"a3"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fake.created != 1 {
+		t.Errorf("created %d albums, want 1", fake.created)
+	}
+
+	// A cached album deleted on the server is recreated.
+	delete(fake.albums, "album-2024-06-02 sony")
+	if err := tr.addToAlbum(t.Context(), group, []string{"a4"}); err != nil {
+		t.Fatal(err)
+	}
+	if fake.created != 2 {
+		t.Errorf("created %d albums, want the deleted one recreated", fake.created)
+	}
+
+	// Any other bad request is reported as it is, keeping the cached album.
+	err := tr.addToAlbum(t.Context(), group, []string{"not-a-uuid"})
+	if err == nil || errors.Is(err, errAlbumGone) || !strings.Contains(err.Error(), "UUID") {
+		t.Errorf("addToAlbum() = %v, want the server's error", err)
+	}
+	if fake.created != 2 {
+		t.Errorf("created %d albums, want no album recreated for a bad request", fake.created)
+	}
+	if _, ok := tr.cache.Album("2024-06-02 sony"); !ok {
+		t.Error("a bad request must not evict the cached album")
+	}
+}
+
+func TestEnsureAlbumCreatesOnce(t *testing.T) {
+	fake := &fakeAlbums{albums: make(map[string]string), members: make(map[string][]string), failCreate: true}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	tr := &Transferer{
+		Client: &Client{Server: srv.URL, Key: "test-key", Retries: 3},
+		cache:  cache.Load(filepath.Join(t.TempDir(), "uploaded.json")),
+	}
+
+	// The server creates the album but the response is lost: retrying the create
+	// would make a duplicate, so the album is found by name instead.
+	id, err := tr.ensureAlbum(t.Context(), "trip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.created != 1 || id != "album-trip" {
+		t.Errorf("created %d album(s), got ID %q; want one album-trip", fake.created, id)
+	}
+}
@@ -0,0 +1,130 @@
+// Package immich uploads date groups straight to an Immich server's API, and
+// triggers library scans.
+package immich
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Client calls one Immich server's API, retrying transient failures.
+type Client struct {
+	Server     string        // API base URL, e.g. https://immich.local/api
+	Key        string        // API key
+	HTTP       *http.Client  // http.DefaultClient when nil
+	Retries    int           // retries for 5xx, 429 and network errors
+	RetryDelay time.Duration // initial delay between retries, doubled per attempt
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTP != nil {
+		return c.HTTP
+	}
+	return http.DefaultClient
+}
+
+// Error is an error response from the Immich API.
+type Error struct {
+	Message    string `json:"message"`
+	ErrType    string `json:"error"`
+	StatusCode int    `json:"statusCode"`
+}
+
+func (e *Error) Error() string {
+	if e == nil {
+		return ""
+	}
+	return e.Message
+}
+
+// ScanLibrary asks the server to scan the external library with ID library.
+func (c *Client) ScanLibrary(ctx context.Context, library string) error {
+	url := c.Server + "/libraries/" + library + "/scan"
+	log.Debug().Str("url", url).Msg("Making request to server")
+	resp, err := c.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("x-api-key", c.Key)
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("triggering library scan: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 204 {
+		var apiErr Error
+		if err := json.Unmarshal(bodyBytes, &apiErr); err == nil {
+			log.Error().
+				Int("status", apiErr.StatusCode).
+				Str("url", url).
+				Str("error", apiErr.Message).
+				Msg("Failed to trigger scan")
+			return fmt.Errorf("triggering library scan: %w", &apiErr)
+		}
+		// fallback if response isn't the expected JSON
+		log.Error().
+			Int("status", resp.StatusCode).
+			Str("url", url).
+			Str("http_body", string(bodyBytes)).
+			Msg("Failed to trigger scan")
+		return fmt.Errorf("triggering library scan: unexpected status %d", resp.StatusCode)
+	}
+
+	log.Info().Msg("sync triggered successfully")
+	return nil
+}
+
+// requestJSON sends a JSON request to the API with retries and decodes the JSON response
+// into out. A status other than want is returned as an *Error.
+func (c *Client) requestJSON(ctx context.Context, method, path string, in any, want int, out any) error {
+	var payload []byte
+	if in != nil {
+		var err error
+		if payload, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	url := c.Server + path
+	resp, err := c.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("x-api-key", c.Key)
+		return req, nil
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != want {
+		apiErr := &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+		_ = json.Unmarshal(body, apiErr)
+		apiErr.StatusCode = resp.StatusCode
+		return apiErr
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("parsing response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package immich
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxRetryDelay caps the backoff between attempts, including server-requested waits.
+const maxRetryDelay = time.Minute
+
+// do sends the request built by newReq, retrying network errors, 5xx responses
+// and 429 Too Many Requests up to c.Retries times with jittered
+// exponential backoff (honouring Retry-After when the server sends one). newReq is
+// called once per attempt so a streamed body can be rebuilt. Once retries are
+// exhausted the last response is returned as is for the caller to report; the
+// caller must close its body. Once ctx is done no further attempt is made.
+func (c *Client) do(ctx context.Context, newReq func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newReq()
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.httpClient().Do(req)
+		var wait time.Duration
+		if err == nil {
+			if !retryableStatus(resp.StatusCode) || attempt >= c.Retries {
+				return resp, nil
+			}
+			wait = retryAfter(resp.Header.Get("Retry-After"), time.Now())
+			err = fmt.Errorf("unexpected status %d", resp.StatusCode)
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		} else if attempt >= c.Retries || ctx.Err() != nil {
+			return nil, err
+		}
+
+		delay := max(backoff(c.RetryDelay, attempt), wait)
+		log.Warn().Err(err).
+			Str("url", req.URL.String()).
+			Int("attempt", attempt+1).
+			Str("delay", delay.Round(time.Millisecond).String()).
+			Msg("request failed, retrying")
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// backoff returns the wait before retry number attempt+1: base doubled per attempt,
+// with the upper half jittered so parallel workers do not retry in step.
+func backoff(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	d := base << min(attempt, 16)
+	if d <= 0 || d > maxRetryDelay {
+		d = maxRetryDelay
+	}
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+// retryAfter parses a Retry-After header given either as seconds or as an HTTP
+// date, returning zero when it is absent or unparseable.
+func retryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	var d time.Duration
+	if secs, err := strconv.Atoi(header); err == nil {
+		d = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(header); err == nil {
+		d = t.Sub(now)
+	}
+	return min(max(d, 0), maxRetryDelay)
+}
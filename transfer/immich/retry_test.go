@@ -0,0 +1,131 @@
+package immich
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{"-3", 0},
+		{"garbage", 0},
+		{now.Add(10 * time.Second).Format(http.TimeFormat), 10 * time.Second},
+		{"3600", maxRetryDelay},
+	}
+	for _, tt := range tests {
+		if got := retryAfter(tt.header, now); got != tt.want {
+			t.Errorf("retryAfter(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	retryDelay := 100 * time.Millisecond
+	for attempt := range 4 {
+		base := retryDelay << attempt
+		for range 20 {
+			if d := backoff(retryDelay, attempt); d < base/2 || d > base {
+				t.Fatalf("backoff(%d) = %v, want within [%v, %v]", attempt, d, base/2, base)
+			}
+		}
+	}
+	if d := backoff(retryDelay, 40); d > maxRetryDelay {
+		t.Errorf("backoff must be capped at %v, got %v", maxRetryDelay, d)
+	}
+}
+
+func TestDoRetries(t *testing.T) {
+	c := &Client{Retries: 3}
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	newReq := func() (*http.Request, error) { return http.NewRequest(http.MethodPost, srv.URL, nil) }
+	resp, err := c.do(t.Context(), newReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent || calls.Load() != 3 {
+		t.Errorf("got status %d after %d calls, want 204 after 3", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestDoGivesUp(t *testing.T) {
+	c := &Client{Retries: 2}
+
+	var calls atomic.Int32
+	status := http.StatusServiceUnavailable
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+	newReq := func() (*http.Request, error) { return http.NewRequest(http.MethodPost, srv.URL, nil) }
+
+	// Retries are exhausted: the final response is handed back to the caller.
+	resp, err := c.do(t.Context(), newReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != status || calls.Load() != 3 {
+		t.Errorf("got status %d after %d calls, want %d after 3", resp.StatusCode, calls.Load(), status)
+	}
+
+	// Client errors are not retried.
+	calls.Store(0)
+	status = http.StatusBadRequest
+	resp, err = c.do(t.Context(), newReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if calls.Load() != 1 {
+		t.Errorf("a 400 response was attempted %d times, want 1", calls.Load())
+	}
+}
+
+func TestDoStopsWhenCancelled(t *testing.T) {
+	c := &Client{Retries: 5, RetryDelay: time.Hour}
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		cancel() // interrupted while waiting to retry
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	newReq := func() (*http.Request, error) { return http.NewRequestWithContext(ctx, http.MethodPost, srv.URL, nil) }
+
+	if _, err := c.do(ctx, newReq); !errors.Is(err, context.Canceled) {
+		t.Errorf("do() error = %v, want context.Canceled", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("made %d requests, want 1", calls.Load())
+	}
+}
@@ -0,0 +1,541 @@
+package immich
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DistroByte/photo-organiser/cache"
+	"github.com/DistroByte/photo-organiser/internal/workpool"
+	"github.com/DistroByte/photo-organiser/organise"
+	"github.com/DistroByte/photo-organiser/progress"
+	"github.com/DistroByte/photo-organiser/transfer"
+	"github.com/rs/zerolog/log"
+)
+
+// Name is the backend name the Transferer reports.
+const Name = "immich"
+
+type uploadResponse struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+type bulkCheckAsset struct {
+	ID       string `json:"id"`
+	Checksum string `json:"checksum"`
+}
+
+type bulkCheckResult struct {
+	ID        string `json:"id"`
+	Action    string `json:"action"` // "accept" or "reject"
+	Reason    string `json:"reason"` // e.g. "duplicate" when rejected
+	AssetID   string `json:"assetId"`
+	IsTrashed bool   `json:"isTrashed"`
+}
+
+// errTrashed fails a file whose only copy on the server is in its trash. Uploading
+// it again is rejected as a duplicate, so it stays on the card until the asset is
+// restored or the trash emptied.
+var errTrashed = errors.New("in the server's trash; restore or empty it, then run again")
+
+// bulkCheckBatch bounds how many checksums are sent per bulk-upload-check request.
+const bulkCheckBatch = 1000
+
+// Transferer uploads each date group straight to the Immich API, skipping
+// files recorded in the upload cache or already on the server.
+type Transferer struct {
+	Client      *Client
+	Album       string // album name template per date group; empty adds no albums
+	Concurrency int    // parallel uploads and checksums
+	DryRun      bool
+	CachePath   string // upload cache file; cache.DefaultPath() when empty
+
+	cache  *cache.Cache
+	failed []string // files that still failed after retries, across all groups
+}
+
+func (*Transferer) Name() string { return Name }
+
+func (t *Transferer) Prepare() error {
+	if t.Client == nil || t.Client.Server == "" || t.Client.Key == "" {
+		return fmt.Errorf("immich needs a server URL and API key")
+	}
+	if t.Album != "" {
+		if _, err := organise.RenderTemplate("album", t.Album, organise.DateGroup{Date: "2006-01-02"}); err != nil {
+			return err
+		}
+	}
+	t.cache = cache.Load(cmp.Or(t.CachePath, cache.DefaultPath()))
+	return nil
+}
+
+// Transfer uploads the group's files and returns those uploaded by this run or
+// confirmed live on the server. Once ctx is done no further upload starts, those
+// in flight are abandoned, and the assets already on the server are still
+// recorded in the cache.
+func (t *Transferer) Transfer(ctx context.Context, group organise.DateGroup) ([]string, error) {
+	log.Info().Str("date", group.Date).Str("source", group.SourceDir).Msg("uploading")
+	result, err := t.uploadGroup(ctx, group)
+	if t.Album != "" && !t.DryRun && len(result.assets) > 0 && ctx.Err() == nil {
+		// Album membership is bookkeeping: the files are safely uploaded either way,
+		// so a failure here is reported without failing the group.
+		if albumErr := t.addToAlbum(ctx, group, result.assetIDs()); albumErr != nil {
+			log.Error().Err(albumErr).Str("date", group.Date).Msg("failed to add assets to album")
+		}
+	}
+	t.cache.Flush()
+	t.failed = append(t.failed, result.failed...)
+	return result.delivered, err
+}
+
+func (t *Transferer) Finalize() error {
+	t.cache.Flush()
+	if len(t.failed) > 0 {
+		log.Error().Int("count", len(t.failed)).Strs("files", t.failed).Msg("files still failing after retries")
+	}
+	return nil
+}
+
+// Verify looks up every file's checksum on the server. A file that is unknown
+// to the server, or only present in its trash, is reported as missing.
+func (t *Transferer) Verify(ctx context.Context, group organise.DateGroup) error {
+	files, err := group.ListFiles()
+	if err != nil {
+		return err
+	}
+	var (
+		items   []uploadItem
+		hashErr error
+		mu      sync.Mutex
+	)
+	workpool.Each(t.Concurrency, files, func(rel string) {
+		if ctx.Err() != nil {
+			return
+		}
+		path := filepath.Join(group.SourceDir, rel)
+		checksum, err := Checksum(path)
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			hashErr = err
+			return
+		}
+		items = append(items, uploadItem{path: path, checksum: checksum})
+	})
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if hashErr != nil {
+		return hashErr
+	}
+
+	var missing int
+	for start := 0; start < len(items); start += bulkCheckBatch {
+		batch := items[start:min(start+bulkCheckBatch, len(items))]
+		results, err := t.Client.bulkUploadCheck(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("checking assets on server: %w", err)
+		}
+		for _, item := range batch {
+			r := results[item.path]
+			if r.Action == "reject" && r.Reason == "duplicate" && !r.IsTrashed {
+				continue
+			}
+			log.Error().Str("file", item.path).Bool("trashed", r.IsTrashed).Str("date", group.Date).Msg("missing on server")
+			missing++
+		}
+	}
+	if missing > 0 {
+		return fmt.Errorf("%d file(s) missing on server", missing)
+	}
+	return nil
+}
+
+// groupUpload is the outcome of uploading one date group.
+type groupUpload struct {
+	assets    map[string]string // path → asset ID of every file now on the server
+	delivered []string          // paths uploaded or confirmed live on the server
+	failed    []string          // paths that failed to upload
+}
+
+// assetIDs returns the distinct asset IDs in the group, in no particular order.
+func (g groupUpload) assetIDs() []string {
+	seen := make(map[string]bool, len(g.assets))
+	ids := make([]string, 0, len(g.assets))
+	for _, id := range g.assets {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// uploadGroup uploads every file in group. The result records the asset ID of
+// every file that is now on the server, whether uploaded by this call or found
+// in the cache or on the server, and the paths of files that failed, which are
+// also summarised in the returned error. Cache hits only count as delivered once
+// the server confirms them, since the asset may have been deleted since. Files
+// left when ctx is done are neither uploaded nor counted as failed, and ctx's
+// error is returned.
+func (t *Transferer) uploadGroup(ctx context.Context, group organise.DateGroup) (groupUpload, error) {
+	files := group.Files
+	if files == nil {
+		entries, err := os.ReadDir(group.SourceDir)
+		if err != nil {
+			return groupUpload{}, fmt.Errorf("reading directory: %w", err)
+		}
+		for _, e := range entries {
+			if !e.IsDir() {
+				files = append(files, e.Name())
+			}
+		}
+	}
+
+	var (
+		result = groupUpload{assets: make(map[string]string)}
+		mu     sync.Mutex
+	)
+	fail := func(path string, err error, msg string) {
+		log.Error().Err(err).Str("file", path).Msg(msg)
+		transfer.Emit(transfer.Event{Name: transfer.EventFileFailed, Backend: Name, Date: group.Date, File: path, Error: err.Error()})
+		mu.Lock()
+		result.failed = append(result.failed, path)
+		mu.Unlock()
+	}
+	done := func(path, id, skipReason string, delivered bool) {
+		if id == "" {
+			return // dry run
+		}
+		if skipReason != "" {
+			transfer.Emit(transfer.Event{Name: transfer.EventFileSkipped, Backend: Name, Date: group.Date, File: path, AssetID: id, Reason: skipReason})
+		} else {
+			transfer.Emit(transfer.Event{Name: transfer.EventFileUploaded, Backend: Name, Date: group.Date, File: path, AssetID: id})
+		}
+		mu.Lock()
+		result.assets[path] = id
+		if delivered {
+			result.delivered = append(result.delivered, path)
+		}
+		mu.Unlock()
+	}
+
+	// Hash every file first: the checksums key both the local cache and the
+	// server-side duplicate check, so only genuinely new files are streamed.
+	var pending []uploadItem
+	workpool.Each(t.Concurrency, files, func(rel string) {
+		if ctx.Err() != nil {
+			return
+		}
+		path := filepath.Join(group.SourceDir, rel)
+		item, err := t.checkCache(path)
+		if err != nil {
+			fail(path, err, "checksum failed")
+			return
+		}
+		mu.Lock()
+		pending = append(pending, item)
+		mu.Unlock()
+	})
+
+	check := t.skipExisting(ctx, pending)
+	for _, item := range check.existing {
+		reason := "on server"
+		if item.cached {
+			reason = "cached"
+		}
+		done(item.path, item.assetID, reason, true)
+	}
+	for _, item := range check.unchecked {
+		done(item.path, item.assetID, "cached", false)
+	}
+	if len(check.unchecked) > 0 {
+		log.Warn().Int("count", len(check.unchecked)).Str("date", group.Date).Msg("cached files not confirmed with the server; leaving them on the card")
+	}
+	for _, item := range check.trashed {
+		fail(item.path, errTrashed, "only in the server's trash")
+	}
+	pending = check.upload
+
+	var p *progress.Upload
+	if !t.DryRun && len(pending) > 0 {
+		var total int64
+		for _, item := range pending {
+			total += item.size
+		}
+		p = progress.StartUpload(len(pending), total)
+	}
+	workpool.Each(t.Concurrency, pending, func(item uploadItem) {
+		if ctx.Err() != nil {
+			p.FileFailed(item.size)
+			return
+		}
+		id, err := t.uploadFile(ctx, item, p)
+		if err != nil {
+			p.FileFailed(item.size)
+			if ctx.Err() == nil {
+				fail(item.path, err, "upload failed")
+			}
+			return
+		}
+		p.FileDone()
+		done(item.path, id, "", true)
+	})
+	p.Finish()
+
+	if err := ctx.Err(); err != nil {
+		log.Warn().Str("date", group.Date).Int("uploaded", len(result.assets)).Msg("upload interrupted")
+		return result, err
+	}
+	if len(result.failed) > 0 {
+		return result, fmt.Errorf("%d file(s) failed to upload", len(result.failed))
+	}
+	return result, nil
+}
+
+// uploadItem is a file to upload unless the server already has it, with its
+// content checksum.
+type uploadItem struct {
+	path     string
+	checksum string // SHA-1 hex, as used by Immich for deduplication
+	size     int64
+	assetID  string // asset ID from the upload cache, or found on the server
+	cached   bool   // assetID came from the upload cache
+}
+
+// checkCache hashes the file at path and looks it up in the cache, recording the
+// cached asset ID for the server to confirm.
+func (t *Transferer) checkCache(path string) (uploadItem, error) {
+	// The cache is keyed by the same SHA-1 Immich uses for deduplication, so a file
+	// is recognised regardless of its name or which camera produced it.
+	checksum, err := Checksum(path)
+	if err != nil {
+		return uploadItem{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return uploadItem{}, err
+	}
+	if id, ok := t.cache.Has(checksum); ok {
+		log.Debug().Str("file", filepath.Base(path)).Str("id", id).Msg("cached, checking with the server")
+		return uploadItem{path: path, checksum: checksum, size: info.Size(), assetID: id, cached: true}, nil
+	}
+	return uploadItem{path: path, checksum: checksum, size: info.Size()}, nil
+}
+
+// uploadFile streams one file to the server, counting the bytes sent towards
+// p, and returns its asset ID, which is empty in a dry run.
+func (t *Transferer) uploadFile(ctx context.Context, item uploadItem, p *progress.Upload) (id string, err error) {
+	path, checksum := item.path, item.checksum
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	if t.DryRun {
+		log.Info().Str("file", filepath.Base(path)).Msg("[dry-run] would upload")
+		return "", nil
+	}
+
+	// fileCreatedAt should reflect when the photo was taken (EXIF), falling back to
+	// mtime; fileModifiedAt stays mtime. PhotoDate handles the EXIF/mtime fallback.
+	created := info.ModTime()
+	if taken, dateErr := organise.PhotoDate(path); dateErr == nil {
+		created = taken
+	}
+
+	// Bytes counted for the current attempt, taken back out of the progress when
+	// the file is sent again or fails.
+	var sent atomic.Int64
+	defer func() {
+		if err != nil {
+			p.Retract(sent.Load())
+		}
+	}()
+
+	url := t.Client.Server + "/assets"
+	resp, err := t.Client.do(ctx, func() (*http.Request, error) {
+		// Each attempt streams the file again from the start.
+		p.Retract(sent.Swap(0))
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		pr, pw := io.Pipe()
+		mw := multipart.NewWriter(pw)
+
+		go func() {
+			defer func() { _ = f.Close() }()
+			fw, err := mw.CreateFormFile("assetData", filepath.Base(path))
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if _, err = io.Copy(fw, p.Reader(f, &sent)); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			for _, pair := range [][2]string{
+				{"deviceAssetId", filepath.Base(path)},
+				{"deviceId", "photo-organiser"},
+				{"fileCreatedAt", created.UTC().Format(time.RFC3339)},
+				{"fileModifiedAt", info.ModTime().UTC().Format(time.RFC3339)},
+				{"isFavorite", "false"},
+			} {
+				if err := mw.WriteField(pair[0], pair[1]); err != nil {
+					pw.CloseWithError(err)
+					return
+				}
+			}
+			if err := mw.Close(); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			_ = pw.Close()
+		}()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, pr)
+		if err != nil {
+			pr.CloseWithError(err)
+			return nil, err
+		}
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.Header.Set("x-api-key", t.Client.Key)
+		// Lets the server recognise a duplicate before reading the body.
+		req.Header.Set("x-immich-checksum", checksum)
+		return req, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 && resp.StatusCode != 201 {
+		return "", fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+
+	var result uploadResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("parsing response: %w", err)
+	}
+
+	// Cache both new uploads and server-side duplicates so future runs skip them.
+	t.cache.Mark(checksum, result.ID)
+
+	if result.Status == "duplicate" {
+		log.Debug().Str("file", filepath.Base(path)).Str("id", result.ID).Msg("duplicate (cached for next run)")
+	} else {
+		log.Debug().Str("file", filepath.Base(path)).Str("id", result.ID).Msg("uploaded")
+	}
+	return result.ID, nil
+}
+
+// existingCheck sorts a group's files by what the server's duplicate check said
+// about them.
+type existingCheck struct {
+	upload    []uploadItem // not on the server, or the check failed
+	existing  []uploadItem // live on the server, with assetID set
+	trashed   []uploadItem // only in the server's trash
+	unchecked []uploadItem // upload cache hits the check failed for
+}
+
+// skipExisting asks the server which of items it already has and caches their
+// asset IDs. This catches files uploaded from another machine or before the local
+// cache was lost, and cache entries whose asset has since been deleted, which are
+// uploaded again. If the check fails, new files are still uploaded, since the
+// upload itself deduplicates, while cache hits are skipped unconfirmed.
+// Files only present in the server's trash are not cached: the server rejects them
+// as duplicates, yet they are not in the library.
+func (t *Transferer) skipExisting(ctx context.Context, items []uploadItem) existingCheck {
+	var check existingCheck
+	for start := 0; start < len(items); start += bulkCheckBatch {
+		batch := items[start:min(start+bulkCheckBatch, len(items))]
+		results, err := t.Client.bulkUploadCheck(ctx, batch)
+		if err != nil {
+			log.Warn().Err(err).Msg("bulk upload check failed, uploading without it")
+			for _, item := range batch {
+				if item.cached {
+					check.unchecked = append(check.unchecked, item)
+				} else {
+					check.upload = append(check.upload, item)
+				}
+			}
+			continue
+		}
+		for _, item := range batch {
+			r, ok := results[item.path]
+			switch {
+			case !ok || r.Action != "reject" || r.Reason != "duplicate" || r.AssetID == "":
+				if item.cached {
+					log.Debug().Str("file", filepath.Base(item.path)).Str("id", item.assetID).Msg("cached asset gone from the server, uploading again")
+				}
+				check.upload = append(check.upload, item)
+			case r.IsTrashed:
+				check.trashed = append(check.trashed, item)
+			default:
+				if r.AssetID != item.assetID {
+					t.cache.Mark(item.checksum, r.AssetID)
+				}
+				item.assetID = r.AssetID
+				check.existing = append(check.existing, item)
+				log.Debug().Str("file", filepath.Base(item.path)).Str("id", r.AssetID).Msg("skipped (already on server)")
+			}
+		}
+	}
+	return check
+}
+
+// bulkUploadCheck calls the server's bulk-upload-check endpoint for items, keyed
+// by path, and returns the results keyed the same way.
+func (c *Client) bulkUploadCheck(ctx context.Context, items []uploadItem) (map[string]bulkCheckResult, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	assets := make([]bulkCheckAsset, len(items))
+	for i, item := range items {
+		assets[i] = bulkCheckAsset{ID: item.path, Checksum: item.checksum}
+	}
+	var parsed struct {
+		Results []bulkCheckResult `json:"results"`
+	}
+	err := c.requestJSON(ctx, http.MethodPost, "/assets/bulk-upload-check", map[string]any{"assets": assets}, http.StatusOK, &parsed)
+	if err != nil {
+		return nil, err
+	}
+	results := make(map[string]bulkCheckResult, len(parsed.Results))
+	for _, r := range parsed.Results {
+		results[r.ID] = r
+	}
+	return results, nil
+}
+
+// Checksum streams a file through SHA-1 and returns the hex digest, the checksum
+// Immich deduplicates assets by.
+func Checksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha1.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,398 @@
+package immich
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DistroByte/photo-organiser/cache"
+	"github.com/DistroByte/photo-organiser/organise"
+	"github.com/DistroByte/photo-organiser/progress"
+	"github.com/DistroByte/photo-organiser/transfer"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fakeImmich serves POST /assets, recording uploaded file names and rejecting any
+// whose name contains "fail", and POST /assets/bulk-upload-check, reporting the
+// checksums in existing as duplicates.
+type fakeImmich struct {
+	mu         sync.Mutex
+	uploaded   []string
+	existing   map[string]string // checksum → asset ID already on the server
+	trashed    map[string]bool   // checksums of existing assets in the server's trash
+	checkFails bool              // bulk-upload-check is rejected
+	inFlight   atomic.Int32
+	peak       atomic.Int32
+}
+
+func (f *fakeImmich) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/assets/bulk-upload-check" {
+		f.bulkUploadCheck(w, r)
+		return
+	}
+
+	n := f.inFlight.Add(1)
+	defer f.inFlight.Add(-1)
+	for {
+		peak := f.peak.Load()
+		if n <= peak || f.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond) // let uploads overlap
+
+	if r.URL.Path != "/assets" || r.Header.Get("x-api-key") != "test-key" {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	_, header, err := r.FormFile("assetData")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if strings.Contains(header.Filename, "fail") {
+		http.Error(w, "nope", http.StatusInternalServerError)
+		return
+	}
+	f.mu.Lock()
+	f.uploaded = append(f.uploaded, header.Filename)
+	f.mu.Unlock()
+	_ = json.NewEncoder(w).Encode(uploadResponse{ID: "id-" + header.Filename, Status: "created"})
+}
+
+func (f *fakeImmich) bulkUploadCheck(w http.ResponseWriter, r *http.Request) {
+	if f.checkFails {
+		http.Error(w, "unavailable", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Assets []bulkCheckAsset `json:"assets"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var resp struct {
+		Results []bulkCheckResult `json:"results"`
+	}
+	for _, a := range req.Assets {
+		result := bulkCheckResult{ID: a.ID, Action: "accept"}
+		if id, ok := f.existing[a.Checksum]; ok {
+			result = bulkCheckResult{ID: a.ID, Action: "reject", Reason: "duplicate", AssetID: id, IsTrashed: f.trashed[a.Checksum]}
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// setupFakeImmich starts a fakeImmich and returns it with a Transferer that
+// uploads to it using an empty cache.
+func setupFakeImmich(t *testing.T) (*fakeImmich, *Transferer) {
+	t.Helper()
+	fake := &fakeImmich{}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	tr := &Transferer{
+		Client: &Client{Server: srv.URL, Key: "test-key"},
+		cache:  cache.Load(filepath.Join(t.TempDir(), "uploaded.json")),
+	}
+	return fake, tr
+}
+
+func TestUploadGroupConcurrent(t *testing.T) {
+	fake, tr := setupFakeImmich(t)
+	tr.Concurrency = 4
+
+	dir := t.TempDir()
+	var files []string
+	for i := range 12 {
+		name := fmt.Sprintf("DSC%05d.JPG", i)
+		writeFile(t, filepath.Join(dir, name), name)
+		files = append(files, name)
+	}
+
+	if _, err := tr.uploadGroup(t.Context(), organise.DateGroup{SourceDir: dir, Files: files, Date: "2024-06-01"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.uploaded) != len(files) {
+		t.Errorf("uploaded %d files, want %d", len(fake.uploaded), len(files))
+	}
+	if fake.peak.Load() < 2 {
+		t.Errorf("peak concurrent uploads = %d, want more than 1", fake.peak.Load())
+	}
+	for _, name := range files {
+		sum, _ := Checksum(filepath.Join(dir, name))
+		if _, ok := tr.cache.Has(sum); !ok {
+			t.Errorf("%s not cached", name)
+		}
+	}
+}
+
+func TestUploadGroupCountsFailures(t *testing.T) {
+	_, tr := setupFakeImmich(t)
+	tr.Concurrency = 3
+
+	dir := t.TempDir()
+	files := []string{"ok1.jpg", "fail1.jpg", "ok2.jpg", "fail2.jpg"}
+	for _, name := range files {
+		writeFile(t, filepath.Join(dir, name), name)
+	}
+
+	result, err := tr.uploadGroup(t.Context(), organise.DateGroup{SourceDir: dir, Files: files, Date: "2024-06-01"})
+	if err == nil || !strings.HasPrefix(err.Error(), "2 file(s)") {
+		t.Errorf("uploadGroup error = %v, want 2 failed files", err)
+	}
+	failed := result.failed
+	sort.Strings(failed)
+	want := []string{filepath.Join(dir, "fail1.jpg"), filepath.Join(dir, "fail2.jpg")}
+	if !slices.Equal(failed, want) {
+		t.Errorf("failed files = %v, want %v", failed, want)
+	}
+	if len(result.assets) != 2 {
+		t.Errorf("got %d uploaded assets, want 2: %v", len(result.assets), result.assets)
+	}
+}
+
+func TestUploadGroupSkipsAssetsOnServer(t *testing.T) {
+	fake, tr := setupFakeImmich(t)
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "new.jpg"), "new photo")
+	writeFile(t, filepath.Join(dir, "known.jpg"), "uploaded elsewhere")
+	known, err := Checksum(filepath.Join(dir, "known.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.existing = map[string]string{known: "server-asset"}
+
+	result, err := tr.uploadGroup(t.Context(), organise.DateGroup{SourceDir: dir, Date: "2024-06-01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := result.assets[filepath.Join(dir, "known.jpg")]; got != "server-asset" {
+		t.Errorf("existing asset ID = %q, want server-asset", got)
+	}
+	if !slices.Equal(fake.uploaded, []string{"new.jpg"}) {
+		t.Errorf("uploaded %v, want only new.jpg", fake.uploaded)
+	}
+	if id, ok := tr.cache.Has(known); !ok || id != "server-asset" {
+		t.Errorf("server-side duplicate should be cached, got (%q, %v)", id, ok)
+	}
+}
+
+func TestUploadGroupConfirmsCacheHits(t *testing.T) {
+	fake, tr := setupFakeImmich(t)
+
+	dir := t.TempDir()
+	live, gone := filepath.Join(dir, "live.jpg"), filepath.Join(dir, "gone.jpg")
+	writeFile(t, live, "still on the server")
+	writeFile(t, gone, "deleted from the server")
+	liveSum, _ := Checksum(live)
+	goneSum, _ := Checksum(gone)
+	tr.cache.Mark(liveSum, "live-asset")
+	tr.cache.Mark(goneSum, "deleted-asset")
+	fake.existing = map[string]string{liveSum: "live-asset"}
+	group := organise.DateGroup{SourceDir: dir, Date: "2024-06-01"}
+
+	result, err := tr.uploadGroup(t.Context(), group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(fake.uploaded, []string{"gone.jpg"}) {
+		t.Errorf("uploaded %v, want the file whose cached asset was deleted", fake.uploaded)
+	}
+	delivered := slices.Sorted(slices.Values(result.delivered))
+	if !slices.Equal(delivered, []string{gone, live}) {
+		t.Errorf("delivered = %v, want both files", delivered)
+	}
+	if id, _ := tr.cache.Has(goneSum); id != "id-gone.jpg" {
+		t.Errorf("stale cache entry = %q, want the new upload's asset ID", id)
+	}
+
+	// Without the server's confirmation, cache hits are skipped but not delivered.
+	fake.checkFails = true
+	fake.uploaded = nil
+	result, err = tr.uploadGroup(t.Context(), group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.uploaded) != 0 || len(result.delivered) != 0 {
+		t.Errorf("uploaded %v, delivered %v; want neither without the check", fake.uploaded, result.delivered)
+	}
+	if got := result.assets[live]; got != "live-asset" {
+		t.Errorf("unconfirmed asset ID = %q, want the cached one for the album", got)
+	}
+}
+
+func TestUploadGroupFailsTrashedAssets(t *testing.T) {
+	fake, tr := setupFakeImmich(t)
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "trashed.jpg"), "deleted on the server")
+	sum, _ := Checksum(filepath.Join(dir, "trashed.jpg"))
+	fake.existing = map[string]string{sum: "trashed-asset"}
+	fake.trashed = map[string]bool{sum: true}
+
+	result, err := tr.uploadGroup(t.Context(), organise.DateGroup{SourceDir: dir, Date: "2024-06-01"})
+	if err == nil {
+		t.Error("expected a trashed duplicate to fail the group")
+	}
+	if !slices.Equal(result.failed, []string{filepath.Join(dir, "trashed.jpg")}) {
+		t.Errorf("failed = %v, want the trashed file", result.failed)
+	}
+	if len(result.assets) != 0 {
+		t.Errorf("trashed file reported as on the server: %v", result.assets)
+	}
+	if _, ok := tr.cache.Has(sum); ok {
+		t.Error("trashed asset should not be cached")
+	}
+}
+
+func TestUploadGroupIgnoresLegacyCacheEntries(t *testing.T) {
+	fake, tr := setupFakeImmich(t)
+
+	// Two version 1 entries, both keyed by name and size only. One is the same
+	// photo, the other belongs to another camera's file that happens to match.
+	// Neither is trusted: both files are checked with the server.
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "DSC00001.ARW"), "same photo")
+	writeFile(t, filepath.Join(dir, "DSC00002.ARW"), "other photo")
+	legacy, _ := json.Marshal(map[string]string{
+		"DSC00001.ARW:10": "asset-1",
+		"DSC00002.ARW:11": "asset-2",
+	})
+	cachePath := filepath.Join(t.TempDir(), "uploaded.json")
+	if err := os.WriteFile(cachePath, legacy, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tr.cache = cache.Load(cachePath)
+	same, _ := Checksum(filepath.Join(dir, "DSC00001.ARW"))
+	fake.existing = map[string]string{same: "asset-1"}
+
+	result, err := tr.uploadGroup(t.Context(), organise.DateGroup{SourceDir: dir, Date: "2024-06-01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(fake.uploaded, []string{"DSC00002.ARW"}) {
+		t.Errorf("uploaded %v, want the file the legacy entry wrongly matched", fake.uploaded)
+	}
+	if got := result.assets[filepath.Join(dir, "DSC00002.ARW")]; got != "id-DSC00002.ARW" {
+		t.Errorf("asset ID = %q, want the new upload's, not the legacy entry's", got)
+	}
+	if id, ok := tr.cache.Has(same); !ok || id != "asset-1" {
+		t.Errorf("confirmed entry should be cached by checksum, got (%q, %v)", id, ok)
+	}
+}
+
+func TestImmichVerify(t *testing.T) {
+	fake, tr := setupFakeImmich(t)
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.jpg"), "photo a")
+	writeFile(t, filepath.Join(dir, "b.jpg"), "photo b")
+	sumA, _ := Checksum(filepath.Join(dir, "a.jpg"))
+	sumB, _ := Checksum(filepath.Join(dir, "b.jpg"))
+	group := organise.DateGroup{SourceDir: dir, Date: "2024-06-01"}
+
+	fake.existing = map[string]string{sumA: "asset-a", sumB: "asset-b"}
+	if err := tr.Verify(t.Context(), group); err != nil {
+		t.Fatalf("verify with every asset present: %v", err)
+	}
+
+	delete(fake.existing, sumB)
+	if err := tr.Verify(t.Context(), group); err == nil {
+		t.Error("expected verification to catch an asset missing on the server")
+	}
+}
+
+func TestUploadGroupEmitsFileEvents(t *testing.T) {
+	fake, tr := setupFakeImmich(t)
+	var (
+		mu     sync.Mutex
+		byFile = make(map[string]transfer.Event)
+	)
+	transfer.Notify = func(ev transfer.Event) {
+		mu.Lock()
+		byFile[filepath.Base(ev.File)] = ev
+		mu.Unlock()
+	}
+	t.Cleanup(func() { transfer.Notify = nil })
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "new.jpg"), "new photo")
+	writeFile(t, filepath.Join(dir, "known.jpg"), "uploaded elsewhere")
+	writeFile(t, filepath.Join(dir, "fail.jpg"), "rejected")
+	known, _ := Checksum(filepath.Join(dir, "known.jpg"))
+	fake.existing = map[string]string{known: "server-asset"}
+
+	_, _ = tr.uploadGroup(t.Context(), organise.DateGroup{SourceDir: dir, Date: "2024-06-01"})
+
+	if ev := byFile["new.jpg"]; ev.Name != transfer.EventFileUploaded || ev.AssetID != "id-new.jpg" {
+		t.Errorf("new.jpg event = %+v", ev)
+	}
+	if ev := byFile["known.jpg"]; ev.Name != transfer.EventFileSkipped || ev.Reason != "on server" {
+		t.Errorf("known.jpg event = %+v", ev)
+	}
+	if ev := byFile["fail.jpg"]; ev.Name != transfer.EventFileFailed || ev.Error == "" {
+		t.Errorf("fail.jpg event = %+v", ev)
+	}
+}
+
+func TestUploadFileRetractsFailedBytes(t *testing.T) {
+	_, tr := setupFakeImmich(t)
+	tr.Client.Retries, tr.Client.RetryDelay = 1, time.Millisecond
+	orig := progress.StderrIsTerminal
+	progress.StderrIsTerminal = func() bool { return false }
+	t.Cleanup(func() { progress.StderrIsTerminal = orig })
+
+	path := filepath.Join(t.TempDir(), "fail.jpg")
+	writeFile(t, path, "rejected by the server")
+	item := uploadItem{path: path, checksum: "abc", size: 22}
+	p := progress.StartUpload(1, item.size)
+
+	if _, err := tr.uploadFile(t.Context(), item, p); err == nil {
+		t.Fatal("expected the upload to fail")
+	}
+	p.Finish()
+	if got := p.String(); !strings.Contains(got, "  0 B / 22 B") {
+		t.Errorf("progress after a failed upload = %q, want nothing sent", got)
+	}
+}
+
+func TestUploadGroupCancelled(t *testing.T) {
+	fake, tr := setupFakeImmich(t)
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.jpg"), "photo a")
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	result, err := tr.uploadGroup(ctx, organise.DateGroup{SourceDir: dir, Date: "2024-06-01"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("uploadGroup() error = %v, want context.Canceled", err)
+	}
+	if len(fake.uploaded) != 0 || len(result.assets) != 0 || len(result.failed) != 0 {
+		t.Errorf("cancelled upload sent %v, result %+v; want nothing sent or failed", fake.uploaded, result)
+	}
+}
@@ -0,0 +1,256 @@
+// Package local copies date groups into a local or mounted directory, such as an
+// external SSD, with the same layout rsync produces on a remote.
+package local
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/DistroByte/photo-organiser/organise"
+	"github.com/DistroByte/photo-organiser/transfer"
+	"github.com/rs/zerolog/log"
+)
+
+// Name is the backend name the Transferer reports.
+const Name = "local"
+
+// Transferer copies each date group into Path/<DestTemplate>. Existing files are
+// never overwritten, and every copy is fsynced and checked against the source.
+type Transferer struct {
+	Path         string // destination root
+	DestTemplate string // directory per group below Path; organise.DefaultDestTemplate when empty
+	DryRun       bool   // log what would be copied without copying it
+}
+
+func (*Transferer) Name() string { return Name }
+
+func (t *Transferer) Prepare() error {
+	if t.Path == "" {
+		return fmt.Errorf("a local copy needs a destination path")
+	}
+	_, err := organise.RenderDest(t.DestTemplate, organise.DateGroup{Date: "2006-01-02"})
+	return err
+}
+
+// Transfer copies the group's files, stopping between files once ctx is done.
+func (t *Transferer) Transfer(ctx context.Context, group organise.DateGroup) ([]string, error) {
+	log.Info().Str("date", group.Date).Str("source", group.SourceDir).Msg("copying")
+	return t.copyGroup(ctx, group)
+}
+
+func (*Transferer) Finalize() error { return nil }
+
+// Verify compares the size and SHA-256 of every file with its copy.
+func (t *Transferer) Verify(ctx context.Context, group organise.DateGroup) error {
+	files, err := group.ListFiles()
+	if err != nil {
+		return err
+	}
+	dest, err := t.dest(group)
+	if err != nil {
+		return err
+	}
+
+	var mismatched int
+	for _, name := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := compareFiles(filepath.Join(group.SourceDir, name), filepath.Join(dest, name)); err != nil {
+			log.Error().Err(err).Str("file", name).Str("date", group.Date).Msg("missing or different in local copy")
+			mismatched++
+		}
+	}
+	if mismatched > 0 {
+		return fmt.Errorf("%d file(s) missing or different in local copy", mismatched)
+	}
+	return nil
+}
+
+// dest returns the directory group is copied into.
+func (t *Transferer) dest(group organise.DateGroup) (string, error) {
+	rel, err := organise.RenderDest(t.DestTemplate, group)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(t.Path, filepath.FromSlash(rel)), nil
+}
+
+// compareFiles returns an error unless dst has the same size and content as src.
+func compareFiles(src, dst string) error {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	dstInfo, err := os.Stat(dst)
+	if err != nil {
+		return err
+	}
+	if srcInfo.Size() != dstInfo.Size() {
+		return fmt.Errorf("size %d, want %d", dstInfo.Size(), srcInfo.Size())
+	}
+	srcSum, err := fileSHA256(src)
+	if err != nil {
+		return err
+	}
+	dstSum, err := fileSHA256(dst)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(srcSum, dstSum) {
+		return fmt.Errorf("checksum mismatch")
+	}
+	return nil
+}
+
+// copyGroup copies group's files and returns the source paths now present at the
+// destination, including those skipped because an identical copy already existed.
+// A different file of the same name is never overwritten; the source is reported
+// as failed and left out, so it stays on the card.
+func (t *Transferer) copyGroup(ctx context.Context, group organise.DateGroup) ([]string, error) {
+	files, err := group.ListFiles()
+	if err != nil {
+		return nil, err
+	}
+	dest, err := t.dest(group)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		done                     []string
+		copied, skipped, clashes int
+	)
+	for _, rel := range files {
+		if err := ctx.Err(); err != nil {
+			return done, err
+		}
+		src, dst := filepath.Join(group.SourceDir, rel), filepath.Join(dest, rel)
+		// Like rsync --ignore-existing, never overwrite what is already there.
+		if _, err := os.Stat(dst); err == nil {
+			if err := compareFiles(src, dst); err != nil {
+				log.Error().Err(err).Str("file", rel).Str("dest", dst).Msg("a different file already exists at the destination; leaving it on the card")
+				transfer.Emit(transfer.Event{Name: transfer.EventFileFailed, Backend: Name, Date: group.Date, File: src, Error: "exists with different content: " + err.Error()})
+				clashes++
+				continue
+			}
+			log.Debug().Str("file", rel).Msg("skipped (exists)")
+			transfer.Emit(transfer.Event{Name: transfer.EventFileSkipped, Backend: Name, Date: group.Date, File: src, Reason: "exists"})
+			done = append(done, src)
+			skipped++
+			continue
+		}
+		if t.DryRun {
+			log.Info().Str("file", rel).Str("dest", dst).Msg("[dry-run] would copy")
+			continue
+		}
+		if err := CopyFile(src, dst); err != nil {
+			transfer.Emit(transfer.Event{Name: transfer.EventFileFailed, Backend: Name, Date: group.Date, File: src, Error: err.Error()})
+			return done, fmt.Errorf("copying %s: %w", rel, err)
+		}
+		transfer.Emit(transfer.Event{Name: transfer.EventFileUploaded, Backend: Name, Date: group.Date, File: src})
+		done = append(done, src)
+		copied++
+	}
+	log.Info().Int("copied", copied).Int("skipped", skipped).Int("clashes", clashes).Str("dest", dest).Msg("group copied")
+	if clashes > 0 {
+		return done, fmt.Errorf("%d file(s) already exist at the destination with different content", clashes)
+	}
+	return done, nil
+}
+
+// CopyFile copies src to dst via a temporary file in the destination directory,
+// fsyncing the data and the directory entry, then re-reads dst and compares its
+// SHA-256 with the one computed while copying. A mismatched copy is removed.
+func CopyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(dst)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(dst)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	h := sha256.New()
+	_, copyErr := io.Copy(io.MultiWriter(tmp, h), in)
+	if copyErr == nil {
+		copyErr = tmp.Chmod(0644)
+	}
+	if copyErr == nil {
+		copyErr = tmp.Sync()
+	}
+	closeErr := tmp.Close()
+	if copyErr != nil {
+		_ = os.Remove(tmpPath)
+		return copyErr
+	}
+	if closeErr != nil {
+		_ = os.Remove(tmpPath)
+		return closeErr
+	}
+
+	// Preserve the modification time, as rsync --archive does.
+	if err := os.Chtimes(tmpPath, info.ModTime(), info.ModTime()); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, dst); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := syncDir(dir); err != nil {
+		return err
+	}
+
+	sum, err := fileSHA256(dst)
+	if err != nil {
+		return fmt.Errorf("verifying copy: %w", err)
+	}
+	if !bytes.Equal(sum, h.Sum(nil)) {
+		_ = os.Remove(dst)
+		return fmt.Errorf("checksum mismatch after copy")
+	}
+	return nil
+}
+
+func fileSHA256(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// syncDir fsyncs a directory so a rename into it survives a power loss.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = d.Close() }()
+	return d.Sync()
+}
@@ -0,0 +1,136 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/DistroByte/photo-organiser/organise"
+)
+
+func writeFile(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !modTime.IsZero() {
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCopyGroup(t *testing.T) {
+	src := t.TempDir()
+	tr := &Transferer{Path: t.TempDir()}
+
+	taken := time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+	writeFile(t, filepath.Join(src, "DSC00001.ARW"), "raw-1", taken)
+	writeFile(t, filepath.Join(src, "DSC00002.ARW"), "raw-2", taken)
+	writeFile(t, filepath.Join(src, "DSC00003.ARW"), "raw-3", taken)
+	// An identical copy is already there from an earlier run.
+	writeFile(t, filepath.Join(tr.Path, "2024-06-01", "DSC00002.ARW"), "raw-2", time.Time{})
+	// A different file of the same name, e.g. from another camera, must be left
+	// untouched (ignore-existing) and its namesake kept on the card.
+	writeFile(t, filepath.Join(tr.Path, "2024-06-01", "DSC00003.ARW"), "other", time.Time{})
+
+	group := organise.DateGroup{SourceDir: src, Date: "2024-06-01"}
+	done, err := tr.copyGroup(t.Context(), group)
+	if err == nil {
+		t.Error("expected an error for the name clash")
+	}
+	// Identical files already at the destination count as delivered, clashes do not.
+	want := []string{filepath.Join(src, "DSC00001.ARW"), filepath.Join(src, "DSC00002.ARW")}
+	if !slices.Equal(done, want) {
+		t.Errorf("copyGroup() = %v, want %v", done, want)
+	}
+
+	copied := filepath.Join(tr.Path, "2024-06-01", "DSC00001.ARW")
+	data, err := os.ReadFile(copied)
+	if err != nil || string(data) != "raw-1" {
+		t.Fatalf("copied file = (%q, %v), want raw-1", data, err)
+	}
+	if info, err := os.Stat(copied); err != nil || !info.ModTime().Equal(taken) {
+		t.Errorf("copied file should keep the source mtime, got %v (%v)", info.ModTime(), err)
+	}
+
+	data, _ = os.ReadFile(filepath.Join(tr.Path, "2024-06-01", "DSC00003.ARW"))
+	if string(data) != "other" {
+		t.Errorf("existing file was overwritten: %q", data)
+	}
+
+	// No temporary files may be left behind.
+	entries, _ := os.ReadDir(filepath.Join(tr.Path, "2024-06-01"))
+	if len(entries) != 3 {
+		t.Errorf("destination has %d entries, want 3", len(entries))
+	}
+}
+
+func TestCopyGroupDryRun(t *testing.T) {
+	src := t.TempDir()
+	tr := &Transferer{Path: t.TempDir(), DryRun: true}
+
+	writeFile(t, filepath.Join(src, "IMG_0001.JPG"), "p", time.Time{})
+	group := organise.DateGroup{SourceDir: src, Files: []string{"IMG_0001.JPG"}, Date: "2024-06-01"}
+	done, err := tr.copyGroup(t.Context(), group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 0 {
+		t.Errorf("dry run reported %v as delivered", done)
+	}
+	if _, err := os.Stat(filepath.Join(tr.Path, "2024-06-01")); !os.IsNotExist(err) {
+		t.Error("dry run must not create anything at the destination")
+	}
+}
+
+func TestCopyGroupCancelled(t *testing.T) {
+	src := t.TempDir()
+	tr := &Transferer{Path: t.TempDir()}
+
+	writeFile(t, filepath.Join(src, "IMG_0001.JPG"), "p", time.Time{})
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	done, err := tr.copyGroup(ctx, organise.DateGroup{SourceDir: src, Date: "2024-06-01"})
+	if !errors.Is(err, context.Canceled) || len(done) != 0 {
+		t.Errorf("copyGroup() = (%v, %v), want nothing copied and context.Canceled", done, err)
+	}
+	if _, err := os.Stat(filepath.Join(tr.Path, "2024-06-01", "IMG_0001.JPG")); !os.IsNotExist(err) {
+		t.Error("a cancelled copy must not copy anything")
+	}
+}
+
+func TestLocalVerify(t *testing.T) {
+	src := t.TempDir()
+	tr := &Transferer{Path: t.TempDir()}
+
+	writeFile(t, filepath.Join(src, "a.jpg"), "photo a", time.Time{})
+	writeFile(t, filepath.Join(src, "b.jpg"), "photo b", time.Time{})
+	group := organise.DateGroup{SourceDir: src, Date: "2024-06-01"}
+	if _, err := tr.copyGroup(t.Context(), group); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Verify(t.Context(), group); err != nil {
+		t.Fatalf("verify after copy: %v", err)
+	}
+
+	// Same size, different content.
+	writeFile(t, filepath.Join(tr.Path, "2024-06-01", "a.jpg"), "photo x", time.Time{})
+	if err := tr.Verify(t.Context(), group); err == nil {
+		t.Error("expected verification to catch a corrupted copy")
+	}
+
+	if err := os.Remove(filepath.Join(tr.Path, "2024-06-01", "b.jpg")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Verify(t.Context(), group); err == nil {
+		t.Error("expected verification to catch a missing copy")
+	}
+}
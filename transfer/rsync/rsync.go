@@ -0,0 +1,233 @@
+// Package rsync syncs date groups to a remote host with rsync over SSH.
+package rsync
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/DistroByte/photo-organiser/organise"
+	"github.com/DistroByte/photo-organiser/transfer"
+	"github.com/rs/zerolog/log"
+)
+
+// Name is the backend name the Transferer reports.
+const Name = "rsync"
+
+// Bin is the rsync executable to run.
+var Bin = "rsync"
+
+// Transferer syncs each date group to Path/<DestTemplate> on Host over SSH.
+type Transferer struct {
+	User         string    // remote user
+	Host         string    // remote host
+	Path         string    // destination root on the remote
+	DestTemplate string    // directory per group below Path; organise.DefaultDestTemplate when empty
+	DryRun       bool      // pass --dry-run to rsync
+	Stdout       io.Writer // receives rsync's progress; os.Stdout when nil
+
+	// checked holds the files each group's checksum pass after Transfer found
+	// missing or different, so Verify does not read every file on both ends again.
+	checked map[string][]string
+}
+
+func (*Transferer) Name() string { return Name }
+
+func (t *Transferer) Prepare() error {
+	if t.Host == "" || t.Path == "" {
+		return fmt.Errorf("rsync needs a remote host and path")
+	}
+	_, err := organise.RenderDest(t.DestTemplate, organise.DateGroup{Date: "2006-01-02"})
+	return err
+}
+
+// Transfer syncs the group, then runs the checksum dry run Verify relies on and
+// reports as sent only the files rsync would not send again. A file that
+// --ignore-existing skipped because a different file of the same name was already
+// there is reported as failed and stays on the card. Nothing is reported sent if
+// rsync fails, since it does not say which files made it. rsync is killed when
+// ctx is done.
+func (t *Transferer) Transfer(ctx context.Context, group organise.DateGroup) ([]string, error) {
+	log.Info().Str("date", group.Date).Str("source", group.SourceDir).Msg("syncing")
+	stdout := t.Stdout
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	if err := t.exec(ctx, group, t.baseArgs(), stdout); err != nil {
+		return nil, err
+	}
+	if t.DryRun {
+		return nil, nil
+	}
+	files, err := group.ListFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := t.check(ctx, group)
+	if err != nil {
+		return nil, fmt.Errorf("checking what arrived: %w", err)
+	}
+	if t.checked == nil {
+		t.checked = make(map[string][]string)
+	}
+	t.checked[checkKey(group)] = names
+	mismatched := make(map[string]bool)
+	for _, name := range names {
+		mismatched[name] = true
+	}
+
+	var done []string
+	for _, rel := range files {
+		src := filepath.Join(group.SourceDir, rel)
+		if mismatched[rel] {
+			log.Error().Str("file", rel).Str("date", group.Date).Msg("missing or different on remote after sync; leaving it on the card")
+			transfer.Emit(transfer.Event{Name: transfer.EventFileFailed, Backend: Name, Date: group.Date, File: src, Error: "missing or different on remote"})
+			continue
+		}
+		transfer.Emit(transfer.Event{Name: transfer.EventFileUploaded, Backend: Name, Date: group.Date, File: src})
+		done = append(done, src)
+	}
+	if len(mismatched) > 0 {
+		return done, fmt.Errorf("%d file(s) missing or different on remote", len(mismatched))
+	}
+	return done, nil
+}
+
+func (*Transferer) Finalize() error { return nil }
+
+// Verify runs a checksum dry run without --ignore-existing: any file rsync would
+// still send is missing on the remote or differs from the source. A group this
+// Transferer has just synced reuses the pass Transfer ran instead of a second one.
+func (t *Transferer) Verify(ctx context.Context, group organise.DateGroup) error {
+	mismatched, ok := t.checked[checkKey(group)]
+	if ok && group.Files != nil {
+		mismatched = slices.DeleteFunc(slices.Clone(mismatched), func(name string) bool {
+			return !slices.Contains(group.Files, name)
+		})
+	}
+	if !ok {
+		var err error
+		if mismatched, err = t.check(ctx, group); err != nil {
+			return err
+		}
+	}
+	for _, name := range mismatched {
+		log.Error().Str("file", name).Str("date", group.Date).Msg("missing or different on remote")
+	}
+	if len(mismatched) > 0 {
+		return fmt.Errorf("%d file(s) missing or different on remote", len(mismatched))
+	}
+	return nil
+}
+
+// check runs the checksum dry run for group and returns the files rsync would
+// still send.
+func (t *Transferer) check(ctx context.Context, group organise.DateGroup) ([]string, error) {
+	var out bytes.Buffer
+	if err := t.exec(ctx, group, verifyArgs(), &out); err != nil {
+		return nil, err
+	}
+	return parseItemizedTransfers(out.String()), nil
+}
+
+// checkKey identifies group in Transferer.checked.
+func checkKey(group organise.DateGroup) string {
+	return group.SourceDir + "\x00" + group.Date
+}
+
+// exec runs rsync with args from group's source (and file list, if any) to its
+// rendered destination, sending rsync's output to stdout.
+func (t *Transferer) exec(ctx context.Context, group organise.DateGroup, args []string, stdout io.Writer) error {
+	rel, err := organise.RenderDest(t.DestTemplate, group)
+	if err != nil {
+		return err
+	}
+	dest := fmt.Sprintf("%s@%s:%s/%s", t.User, t.Host, t.Path, rel)
+	source := group.SourceDir
+	if !strings.HasSuffix(source, string(os.PathSeparator)) {
+		source += string(os.PathSeparator)
+	}
+
+	if strings.Contains(rel, "/") {
+		// rsync only creates the last directory of the destination by itself.
+		args = append(args, "--mkpath")
+	}
+
+	if group.Files != nil {
+		tmp, err := os.CreateTemp("", "photo-organiser-*.txt")
+		if err != nil {
+			return fmt.Errorf("creating file list: %w", err)
+		}
+		defer func() { _ = os.Remove(tmp.Name()) }()
+
+		if _, err := fmt.Fprintln(tmp, strings.Join(group.Files, "\n")); err != nil {
+			_ = tmp.Close()
+			return fmt.Errorf("writing file list: %w", err)
+		}
+		if err := tmp.Close(); err != nil {
+			return fmt.Errorf("closing file list: %w", err)
+		}
+		args = append(args, "--files-from="+tmp.Name())
+	}
+
+	args = append(args, source, dest)
+	cmd := exec.CommandContext(ctx, Bin, args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return nil
+}
+
+func (t *Transferer) baseArgs() []string {
+	flags := []string{"--archive", "--verbose", "--human-readable"}
+	if t.DryRun {
+		flags = append(flags, "--dry-run")
+	}
+	return append([]string{
+		"--rsync-path=/bin/rsync",
+		"--protect-args",
+		"--ignore-existing",
+		"--info=none,progress2",
+		"--chmod=ugo=rwX",
+	}, flags...)
+}
+
+func verifyArgs() []string {
+	return []string{
+		"--rsync-path=/bin/rsync",
+		"--protect-args",
+		"--archive",
+		"--checksum",
+		"--dry-run",
+		"--out-format=%i %n",
+	}
+}
+
+// parseItemizedTransfers returns the names of regular files that an itemized
+// (--out-format=%i %n) rsync run would send. Attribute-only updates start with
+// "." and are ignored.
+func parseItemizedTransfers(output string) []string {
+	var names []string
+	for _, line := range strings.Split(output, "\n") {
+		item, name, ok := strings.Cut(line, " ")
+		if !ok || len(item) < 2 || item[1] != 'f' {
+			continue
+		}
+		if item[0] == '<' || item[0] == '>' {
+			names = append(names, name)
+		}
+	}
+	return names
+}
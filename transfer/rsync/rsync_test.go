@@ -0,0 +1,62 @@
+package rsync
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/DistroByte/photo-organiser/organise"
+)
+
+func TestParseItemizedTransfers(t *testing.T) {
+	output := `cd+++++++++ ./
+<f+++++++++ DSC00001.ARW
+<fcs....... DSC00002.ARW
+.f...p..... DSC00003.ARW
+.d..t...... sub/
+>f.st...... sub/file with spaces.JPG
+`
+	want := []string{"DSC00001.ARW", "DSC00002.ARW", "sub/file with spaces.JPG"}
+	if got := parseItemizedTransfers(output); !slices.Equal(got, want) {
+		t.Errorf("parseItemizedTransfers() = %v, want %v", got, want)
+	}
+	if got := parseItemizedTransfers(""); len(got) != 0 {
+		t.Errorf("empty output should yield no files, got %v", got)
+	}
+}
+
+func TestTransferLeavesMismatchedFiles(t *testing.T) {
+	// A fake rsync: the sync succeeds, and the checksum dry run reports that
+	// b.jpg still differs, as after --ignore-existing skipped a name clash.
+	dir := t.TempDir()
+	bin, checks := filepath.Join(dir, "rsync"), filepath.Join(dir, "checks")
+	script := "#!/bin/sh\ncase \"$*\" in *--checksum*) echo x >> " + checks + "; echo '<fc.T...... b.jpg' ;; esac\n"
+	if err := os.WriteFile(bin, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	orig := Bin
+	Bin = bin
+	t.Cleanup(func() { Bin = orig })
+
+	tr := &Transferer{User: "u", Host: "h", Path: "/photos", Stdout: os.Stderr}
+	group := organise.DateGroup{SourceDir: "/card/100", Files: []string{"a.jpg", "b.jpg"}, Date: "2024-06-01"}
+	done, err := tr.Transfer(t.Context(), group)
+	if err == nil {
+		t.Error("expected an error for the file that did not arrive intact")
+	}
+	if want := []string{"/card/100/a.jpg"}; !slices.Equal(done, want) {
+		t.Errorf("Transfer() = %v, want only %v", done, want)
+	}
+
+	// Verify reuses the checksum pass Transfer ran.
+	if err := tr.Verify(t.Context(), group); err == nil {
+		t.Error("expected Verify to report b.jpg")
+	}
+	if err := tr.Verify(t.Context(), organise.DateGroup{SourceDir: "/card/100", Files: []string{"a.jpg"}, Date: "2024-06-01"}); err != nil {
+		t.Errorf("Verify() of the delivered file = %v", err)
+	}
+	if data, _ := os.ReadFile(checks); string(data) != "x\n" {
+		t.Errorf("ran the checksum pass %d times, want once", len(data)/2)
+	}
+}
@@ -0,0 +1,181 @@
+// Package transfer sends date groups to one or more destinations through the
+// Transferer interface, which the rsync, local and immich packages implement.
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/DistroByte/photo-organiser/organise"
+	"github.com/rs/zerolog/log"
+)
+
+// Transferer sends date groups to one destination. A run calls Prepare once,
+// Transfer for every group, then Finalize, even if some groups failed or the run
+// was cancelled. Verify can then be called for every group. Transfer and Verify
+// should return promptly once ctx is done.
+type Transferer interface {
+	Name() string
+	Prepare() error // validate settings and load any state
+	// Transfer sends one group and returns the source paths that are now safely
+	// at the destination, which may be only some of them when it fails.
+	Transfer(ctx context.Context, group organise.DateGroup) ([]string, error)
+	Finalize() error                                            // persist state once all groups are done
+	Verify(ctx context.Context, group organise.DateGroup) error // confirm every file in the group arrived intact
+}
+
+// result summarises one backend's run over all groups.
+type result struct {
+	backend   string
+	groups    int
+	failed    int
+	err       error // set when Prepare or Finalize failed
+	elapsed   time.Duration
+	delivered map[string]bool // source paths the backend reported as sent
+}
+
+func (r result) ok() bool {
+	return r.err == nil && r.failed == 0
+}
+
+// Run sends every group through each backend in turn. A failing group does not
+// stop the others or the remaining backends; every backend's result is reported
+// at the end, and an error is returned if any of them failed. The returned source
+// paths are those every backend delivered, and so are the only files that are
+// safe to remove from the card. Once ctx is done no further group is started, and
+// the partial results are reported and returned with ctx's error.
+func Run(ctx context.Context, groups []organise.DateGroup, transferers []Transferer) ([]string, error) {
+	if len(groups) == 0 {
+		log.Warn().Msg("no files to transfer")
+		return nil, nil
+	}
+
+	results := make([]result, 0, len(transferers))
+	for _, t := range transferers {
+		results = append(results, runTransferer(ctx, t, groups))
+	}
+
+	var failed int
+	for _, r := range results {
+		entry := log.Info()
+		if !r.ok() {
+			entry = log.Error().Err(r.err)
+			failed++
+		}
+		entry.Str("backend", r.backend).
+			Int("groups", r.groups).
+			Int("failed", r.failed).
+			Str("elapsed", r.elapsed.Round(time.Millisecond).String()).
+			Msg("transfer summary")
+		Emit(Event{
+			Name:    EventBackendSummary,
+			Backend: r.backend,
+			Groups:  r.groups,
+			Failed:  r.failed,
+			Error:   ErrString(r.err),
+			Elapsed: r.elapsed.Seconds(),
+		})
+	}
+
+	var transferred []string
+	if len(results) > 0 {
+		for path := range results[0].delivered {
+			if !slices.ContainsFunc(results[1:], func(r result) bool { return !r.delivered[path] }) {
+				transferred = append(transferred, path)
+			}
+		}
+		slices.Sort(transferred)
+	}
+	if err := ctx.Err(); err != nil {
+		return transferred, fmt.Errorf("transfer interrupted: %w", err)
+	}
+	if failed > 0 {
+		return transferred, fmt.Errorf("%d of %d backend(s) failed", failed, len(results))
+	}
+	return transferred, nil
+}
+
+func runTransferer(ctx context.Context, t Transferer, groups []organise.DateGroup) result {
+	start := time.Now()
+	res := result{backend: t.Name(), groups: len(groups), delivered: make(map[string]bool)}
+	// A backend that never starts delivers nothing, so nothing is cleaned up.
+	if err := ctx.Err(); err != nil {
+		res.err = err
+		return res
+	}
+	if err := t.Prepare(); err != nil {
+		res.err = err
+		res.elapsed = time.Since(start)
+		return res
+	}
+	for _, group := range groups {
+		if ctx.Err() != nil {
+			res.err = ctx.Err()
+			break
+		}
+		Emit(Event{
+			Name:    EventGroupStarted,
+			Camera:  group.Camera,
+			Backend: t.Name(),
+			Date:    group.Date,
+			Source:  group.SourceDir,
+			Files:   len(group.Files),
+		})
+		done, err := t.Transfer(ctx, group)
+		if err != nil {
+			log.Error().Err(err).Str("backend", t.Name()).Str("date", group.Date).Msg("transfer failed")
+			res.failed++
+		}
+		Emit(Event{
+			Name:        EventGroupDone,
+			Camera:      group.Camera,
+			Backend:     t.Name(),
+			Date:        group.Date,
+			Transferred: len(done),
+			Error:       ErrString(err),
+		})
+		for _, path := range done {
+			res.delivered[path] = true
+		}
+	}
+	if err := t.Finalize(); err != nil {
+		res.err = err
+	}
+	if ctx.Err() != nil {
+		res.err = ctx.Err()
+	}
+	res.elapsed = time.Since(start)
+	return res
+}
+
+// Verify asks every backend to confirm every group, logging a result per backend,
+// and returns an error if anything is missing or differs, or ctx's error if it is
+// done before every group has been checked.
+func Verify(ctx context.Context, groups []organise.DateGroup, transferers []Transferer) error {
+	var failed int
+	for _, t := range transferers {
+		var bad int
+		for _, group := range groups {
+			if err := ctx.Err(); err != nil {
+				return fmt.Errorf("verification interrupted: %w", err)
+			}
+			if err := t.Verify(ctx, group); err != nil {
+				log.Error().Err(err).Str("backend", t.Name()).Str("date", group.Date).Msg("verification failed")
+				bad++
+			}
+		}
+		entry := log.Info()
+		if bad > 0 {
+			entry = log.Error()
+			failed++
+		}
+		entry.Str("backend", t.Name()).Int("groups", len(groups)).Int("failed", bad).Msg("verification summary")
+		Emit(Event{Name: EventVerification, Backend: t.Name(), Groups: len(groups), Failed: bad})
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d backend(s) failed verification", failed, len(transferers))
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/DistroByte/photo-organiser/organise"
+)
+
+// fakeTransferer records the groups it is given and fails the dates in failDates,
+// reporting a group's files as delivered only when it succeeds. Verify fails the
+// dates in missingDates. When set, after is called once each group is sent.
+type fakeTransferer struct {
+	name         string
+	prepareErr   error
+	failDates    map[string]bool
+	missingDates map[string]bool
+	after        func()
+	sent         []string
+	finalized    bool
+}
+
+func (f *fakeTransferer) Name() string   { return f.name }
+func (f *fakeTransferer) Prepare() error { return f.prepareErr }
+func (f *fakeTransferer) Finalize() error {
+	f.finalized = true
+	return nil
+}
+
+func (f *fakeTransferer) Verify(ctx context.Context, group organise.DateGroup) error {
+	if f.missingDates[group.Date] {
+		return errors.New("missing")
+	}
+	return nil
+}
+
+func (f *fakeTransferer) Transfer(ctx context.Context, group organise.DateGroup) ([]string, error) {
+	f.sent = append(f.sent, group.Date)
+	if f.after != nil {
+		defer f.after()
+	}
+	if f.failDates[group.Date] {
+		return nil, errors.New("boom")
+	}
+	var done []string
+	for _, rel := range group.Files {
+		done = append(done, filepath.Join(group.SourceDir, rel))
+	}
+	return done, nil
+}
+
+func TestRunUsesEveryBackend(t *testing.T) {
+	groups := []organise.DateGroup{{Date: "2024-06-01"}, {Date: "2024-06-02"}}
+	good := &fakeTransferer{name: "good"}
+	flaky := &fakeTransferer{name: "flaky", failDates: map[string]bool{"2024-06-01": true}}
+	broken := &fakeTransferer{name: "broken", prepareErr: errors.New("not configured")}
+
+	_, err := Run(context.Background(), groups, []Transferer{flaky, broken, good})
+	if err == nil {
+		t.Fatal("expected an error when a backend fails")
+	}
+
+	// A failed group must not stop the remaining groups or backends.
+	if !slices.Equal(flaky.sent, []string{"2024-06-01", "2024-06-02"}) {
+		t.Errorf("flaky backend sent %v, want both groups", flaky.sent)
+	}
+	if !slices.Equal(good.sent, []string{"2024-06-01", "2024-06-02"}) {
+		t.Errorf("good backend sent %v, want both groups", good.sent)
+	}
+	if len(broken.sent) != 0 || broken.finalized {
+		t.Error("a backend that fails Prepare must not transfer or finalize")
+	}
+	if !flaky.finalized || !good.finalized {
+		t.Error("backends that prepared successfully must be finalized")
+	}
+
+	if _, err := Run(context.Background(), groups, []Transferer{&fakeTransferer{name: "ok"}}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRunReportsFilesEveryBackendDelivered(t *testing.T) {
+	groups := []organise.DateGroup{
+		{Date: "2024-06-01", Files: []string{"a.jpg", "b.jpg"}},
+		{Date: "2024-06-02", Files: []string{"c.jpg"}},
+	}
+	good := &fakeTransferer{name: "good"}
+	flaky := &fakeTransferer{name: "flaky", failDates: map[string]bool{"2024-06-01": true}}
+
+	got, err := Run(context.Background(), groups, []Transferer{good, flaky})
+	if err == nil {
+		t.Error("expected an error when a backend fails")
+	}
+	if !slices.Equal(got, []string{"c.jpg"}) {
+		t.Errorf("transferred = %v, want only the group both backends delivered", got)
+	}
+
+	broken := &fakeTransferer{name: "broken", prepareErr: errors.New("not configured")}
+	if got, _ := Run(context.Background(), groups, []Transferer{good, broken}); len(got) != 0 {
+		t.Errorf("transferred = %v, want nothing when a backend could not run", got)
+	}
+}
+
+func TestRunEmitsEvents(t *testing.T) {
+	var events []Event
+	Notify = func(ev Event) { events = append(events, ev) }
+	t.Cleanup(func() { Notify = nil })
+
+	groups := []organise.DateGroup{{SourceDir: "/card", Files: []string{"a.jpg"}, Date: "2024-06-01", Camera: "sony"}}
+	flaky := &fakeTransferer{name: "flaky", failDates: map[string]bool{"2024-06-01": true}}
+	_, _ = Run(context.Background(), groups, []Transferer{flaky})
+
+	var names []string
+	for _, ev := range events {
+		names = append(names, ev.Name)
+	}
+	want := []string{EventGroupStarted, EventGroupDone, EventBackendSummary}
+	if !slices.Equal(names, want) {
+		t.Fatalf("events = %v, want %v", names, want)
+	}
+	if events[0].Camera != "sony" || events[0].Files != 1 || events[0].Backend != "flaky" {
+		t.Errorf("group_started = %+v", events[0])
+	}
+	if events[1].Error != "boom" || events[2].Failed != 1 {
+		t.Errorf("failure not reported: %+v, %+v", events[1], events[2])
+	}
+}
+
+func TestVerify(t *testing.T) {
+	groups := []organise.DateGroup{{Date: "2024-06-01"}, {Date: "2024-06-02"}}
+	good := &fakeTransferer{name: "good"}
+	if err := Verify(context.Background(), groups, []Transferer{good}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	bad := &fakeTransferer{name: "bad", missingDates: map[string]bool{"2024-06-02": true}}
+	if err := Verify(context.Background(), groups, []Transferer{good, bad}); err == nil {
+		t.Error("expected an error when a backend is missing files")
+	}
+}
+
+func TestRunStopsWhenCancelled(t *testing.T) {
+	groups := []organise.DateGroup{
+		{SourceDir: "/card", Files: []string{"a.jpg"}, Date: "2024-06-01"},
+		{SourceDir: "/card", Files: []string{"b.jpg"}, Date: "2024-06-02"},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	first := &fakeTransferer{name: "first", after: cancel}
+	second := &fakeTransferer{name: "second"}
+
+	got, err := Run(ctx, groups, []Transferer{first, second})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want context.Canceled", err)
+	}
+	if !slices.Equal(first.sent, []string{"2024-06-01"}) || !first.finalized {
+		t.Errorf("first backend sent %v (finalized %v), want one group then finalize", first.sent, first.finalized)
+	}
+	if len(second.sent) != 0 {
+		t.Errorf("second backend sent %v after cancellation", second.sent)
+	}
+	if len(got) != 0 {
+		t.Errorf("delivered %v, want nothing: the second backend never ran", got)
+	}
+}
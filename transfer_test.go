@@ -0,0 +1,49 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/DistroByte/photo-organiser/organise"
+	"github.com/DistroByte/photo-organiser/transfer"
+)
+
+func TestSelectBackends(t *testing.T) {
+	t.Cleanup(func() {
+		immichServer, immichKey, remoteHost, remotePath, localPath = "", "", "", "", ""
+	})
+
+	names := func(ts []transfer.Transferer) []string {
+		var out []string
+		for _, tr := range ts {
+			out = append(out, tr.Name())
+		}
+		return out
+	}
+
+	immichServer, immichKey = "https://immich.local/api", "key"
+	remoteHost, remotePath = "nas", "/photos"
+
+	// Immich wins by default, but jobs that cannot upload fall back to rsync.
+	got, err := selectBackends(organise.Sony, nil)
+	if err != nil || !slices.Equal(names(got), []string{backendImmich}) {
+		t.Errorf("default = (%v, %v), want [immich]", names(got), err)
+	}
+	got, err = selectBackends(organise.SonyVideo, nil)
+	if err != nil || !slices.Equal(names(got), []string{backendRsync}) {
+		t.Errorf("noImmich default = (%v, %v), want [rsync]", names(got), err)
+	}
+
+	explicit := []string{backendRsync, backendImmich, backendRsync}
+	got, err = selectBackends(organise.Sony, explicit)
+	if err != nil || !slices.Equal(names(got), []string{backendRsync, backendImmich}) {
+		t.Errorf("explicit = (%v, %v), want [rsync immich]", names(got), err)
+	}
+	if _, err := selectBackends(organise.SonyVideo, explicit); err == nil {
+		t.Error("expected an error selecting immich for a noImmich job")
+	}
+
+	if _, err := selectBackends(organise.Sony, []string{"ftp"}); err == nil {
+		t.Error("expected an error for an unknown backend")
+	}
+}
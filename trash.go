@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/DistroByte/photo-organiser/organise"
+	"github.com/DistroByte/photo-organiser/pipeline"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// trashCard is the --trash value for a trash folder at the root of the card.
+const trashCard = "card"
+
+// trashDir resolves --trash to the directory cleaned files are moved into, or ""
+// when they are deleted outright.
+func trashDir() string {
+	switch trashPath {
+	case "":
+		return ""
+	case trashCard:
+		return filepath.Join(directory, organise.TrashDirName)
+	}
+	return trashPath
+}
+
+func runPurge(cmd *cobra.Command, args []string) error {
+	if trashPath == "" {
+		return fmt.Errorf("provide --trash (a directory, or \"card\")")
+	}
+	p := newPipeline()
+	purge := func() error {
+		purged, err := p.PurgeTrash(time.Now())
+		log.Info().Int("purged", purged).Int("retention_days", trashRetention).Str("trash", p.Trash).Msg("trash purged")
+		return pipeline.StageError(pipeline.StageCleanup, err)
+	}
+	if trashPath == trashCard {
+		return p.WithCard(false, purge)
+	}
+	return purge()
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/DistroByte/photo-organiser/mount"
+	"github.com/rs/zerolog/log"
+)
+
+// cameraAuto in the config's cards section offloads every camera detected on the
+// card, as the auto command does.
+const cameraAuto = "auto"
+
+// runWatch checks the configured cards, then imports each one as it is attached
+// until ctx is cancelled. Prompts are never shown: "ask" falls back to
+// --cleanup-unattended.
+func runWatch(ctx context.Context, cards map[string]string) error {
+	if len(cards) == 0 {
+		return fmt.Errorf("no cards to watch for: add a cards section to the config file")
+	}
+	for card, camera := range cards {
+		if _, ok := camerasByName[camera]; !ok && camera != cameraAuto {
+			return fmt.Errorf("card %q: unknown camera %q", card, camera)
+		}
+	}
+	if pollInterval <= 0 {
+		return fmt.Errorf("--poll-interval must be positive, got %s", pollInterval)
+	}
+	unattended = true
+	return watch(ctx, cards, pollInterval, importCard)
+}
+
+// watch polls /dev/disk every interval and calls run for each newly attached disk
+// whose volume label or UUID is one of cards. Disks already attached when it
+// starts are left alone, and a disk is only imported again once it has been
+// removed and reattached. A failed import is logged and watching carries on; an
+// interrupted one is returned.
+func watch(ctx context.Context, cards map[string]string, interval time.Duration, run func(context.Context, mount.Disk, string) error) error {
+	seen := make(map[mount.Disk]bool)
+	for _, d := range mount.Disks() {
+		seen[d] = true
+	}
+	log.Info().Int("cards", len(cards)).Dur("interval", interval).Msg("watching for cards")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("stopped watching for cards")
+			return nil
+		case <-time.After(interval):
+		}
+
+		attached := make(map[mount.Disk]bool)
+		for _, d := range mount.Disks() {
+			attached[d] = true
+			if seen[d] {
+				continue
+			}
+			camera, ok := matchCard(d, cards)
+			if !ok {
+				log.Debug().Str("device", d.Device).Str("label", d.Label).Str("uuid", d.UUID).Msg("ignoring device: not a configured card")
+				continue
+			}
+			log.Info().Str("device", d.Device).Str("label", d.Label).Str("uuid", d.UUID).Str("camera", camera).Msg("card attached")
+			if err := run(ctx, d, camera); err != nil {
+				log.Error().Err(err).Str("device", d.Device).Msg("import failed")
+				if ctx.Err() != nil {
+					return err
+				}
+			} else {
+				log.Info().Str("device", d.Device).Msg("import finished; the card can be removed")
+			}
+		}
+		seen = attached
+	}
+}
+
+// matchCard returns the camera configured for d, looked up by volume label first
+// and then by UUID.
+func matchCard(d mount.Disk, cards map[string]string) (string, bool) {
+	for _, key := range []string{d.Label, d.UUID} {
+		if camera, ok := cards[key]; ok && key != "" {
+			return camera, true
+		}
+	}
+	return "", false
+}
+
+// importCard offloads d as if it had been given with --device, running camera's
+// job or, for "auto", every camera detected on it.
+func importCard(ctx context.Context, d mount.Disk, camera string) error {
+	savedDevice, savedSource := device, sourceDir
+	defer func() { device, sourceDir = savedDevice, savedSource }()
+	device = d.Device
+
+	if camera == cameraAuto {
+		return offloadDetected(ctx)
+	}
+	return offload(ctx, camerasByName[camera])
+}
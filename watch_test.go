@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DistroByte/photo-organiser/mount"
+)
+
+func TestMatchCard(t *testing.T) {
+	cards := map[string]string{"SONY A7": "sony", "ABCD-1234": "auto"}
+	for _, tc := range []struct {
+		disk   mount.Disk
+		camera string
+		ok     bool
+	}{
+		{mount.Disk{Label: "SONY A7", UUID: "ABCD-1234"}, "sony", true}, // the label wins
+		{mount.Disk{Label: "OTHER", UUID: "ABCD-1234"}, "auto", true},
+		{mount.Disk{UUID: "5678-EF90"}, "", false},
+		{mount.Disk{}, "", false},
+	} {
+		camera, ok := matchCard(tc.disk, cards)
+		if camera != tc.camera || ok != tc.ok {
+			t.Errorf("matchCard(%+v) = (%q, %v), want (%q, %v)", tc.disk, camera, ok, tc.camera, tc.ok)
+		}
+	}
+}
+
+func TestWatchImportsNewCards(t *testing.T) {
+	devs := t.TempDir()
+	mount.DiskByUUID, mount.DiskByLabel = t.TempDir(), t.TempDir()
+	t.Cleanup(func() { mount.DiskByUUID, mount.DiskByLabel = "/dev/disk/by-uuid", "/dev/disk/by-label" })
+	attach := func(dev, label string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(devs, dev), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(filepath.Join(devs, dev), filepath.Join(mount.DiskByLabel, label)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Already attached when watching starts, so never imported.
+	attach("sdd1", "SONY")
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	imported := make(chan string, 4)
+	done := make(chan error, 1)
+	go func() {
+		done <- watch(ctx, map[string]string{"SONY": "sony", "DJI": "dji"}, time.Millisecond, func(ctx context.Context, d mount.Disk, camera string) error {
+			imported <- d.Label + ":" + camera
+			return nil
+		})
+	}()
+
+	// Let watch list the disks already attached first.
+	time.Sleep(50 * time.Millisecond)
+	attach("sde1", "USB STICK") // not a configured card
+	attach("sdf1", "DJI")
+	select {
+	case got := <-imported:
+		if got != "DJI:dji" {
+			t.Errorf("imported %q, want DJI:dji", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the attached card was never imported")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("watch() = %v, want nil once stopped", err)
+	}
+	if len(imported) != 0 {
+		t.Errorf("imported %q as well; a card is imported once per attachment", <-imported)
+	}
+}